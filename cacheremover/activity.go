@@ -0,0 +1,46 @@
+package cacheremover
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// LastActivity returns when a project was last worked on: the newest
+// modification time of its own files, ignoring cache directories (including
+// those with a CACHEDIR.TAG), cache files, mount points and the .git
+// directory. With GitActivity set, the last commit of the enclosing
+// repository also counts. It returns the zero time for a project with no
+// source files.
+func (s *Scanner) LastActivity(ctx context.Context, projectPath string, config CacheConfig) (time.Time, error) {
+	ps := newProjectScan(projectPath, config)
+	ps.project = &Project{Path: projectPath}
+	ps.activity = true
+	err := s.newWalk(ctx, false, true).walkProject(ps, false)
+	return ps.project.LastActivity, err
+}
+
+func matchesAnyDir(patterns []*pattern, projectPath, dirPath string) bool {
+	for _, p := range patterns {
+		if p.matchDir(projectPath, dirPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCacheFile reports whether the file at rel, relative to the project
+// root, matches one of the cache file patterns or extensions
+func isCacheFile(rel string, files []*pattern, extensions []string) bool {
+	for _, file := range files {
+		if file.matchPath(rel) {
+			return true
+		}
+	}
+	for _, ext := range extensions {
+		if strings.HasSuffix(rel, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLastActivity(t *testing.T) {
+	scanner := newTestScanner()
+	projectDir := t.TempDir()
+
+	source := filepath.Join(projectDir, "main.py")
+	compiled := filepath.Join(projectDir, "main.pyc")
+	cached := filepath.Join(projectDir, "__pycache__", "main.cpython-39.pyc")
+	os.MkdirAll(filepath.Dir(cached), 0755)
+	for _, path := range []string{source, compiled, cached} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	// Only the source file is old; cache files were just regenerated
+	sourceTime := time.Now().Add(-90 * 24 * time.Hour).Truncate(time.Second)
+	os.Chtimes(source, sourceTime, sourceTime)
+
+	config := DefaultConfig().ProjectTypes[1].CacheConfig // Python
+	latest, err := scanner.LastActivity(context.Background(), projectDir, config)
+	if err != nil {
+		t.Fatalf("LastActivity failed: %v", err)
+	}
+	if !latest.Equal(sourceTime) {
+		t.Errorf("Expected last activity %v, got %v", sourceTime, latest)
+	}
+}
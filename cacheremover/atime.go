@@ -0,0 +1,25 @@
+package cacheremover
+
+import (
+	"os"
+	"time"
+)
+
+// lastUsed returns when a file was last read or written: the later of its
+// access and modification times. Filesystems mounted with noatime, or
+// platforms without access times, fall back to the modification time;
+// readSinceWritten tells whether the access times can be relied on.
+func lastUsed(info os.FileInfo) time.Time {
+	if atime, ok := accessTime(info); ok && atime.After(info.ModTime()) {
+		return atime
+	}
+	return info.ModTime()
+}
+
+// readSinceWritten reports whether a file was read after it was last
+// written. Filesystems that do not record access times, such as those
+// mounted with noatime, never show that.
+func readSinceWritten(info os.FileInfo) bool {
+	atime, ok := accessTime(info)
+	return ok && atime.After(info.ModTime())
+}
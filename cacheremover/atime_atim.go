@@ -0,0 +1,17 @@
+//go:build linux || openbsd || dragonfly
+
+package cacheremover
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)), true
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package cacheremover
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds()), true
+}
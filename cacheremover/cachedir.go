@@ -0,0 +1,48 @@
+package cacheremover
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CacheDirTagName is the marker file of the Cache Directory Tagging
+// Specification (https://bford.info/cachedir/). Cargo, many build tools
+// and backup software write or honour it.
+const CacheDirTagName = "CACHEDIR.TAG"
+
+// cacheDirTagSignature is the line a valid tag file must start with
+const cacheDirTagSignature = "Signature: 8a477f597d28d172789f06886806bc55"
+
+// HasCacheDirTag reports whether dir contains a CACHEDIR.TAG file that
+// starts with the signature the specification requires
+func HasCacheDirTag(dir string) bool {
+	f, err := os.Open(filepath.Join(dir, CacheDirTagName))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, len(cacheDirTagSignature))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, []byte(cacheDirTagSignature))
+}
+
+// WriteCacheDirTag marks dir as a cache directory so that backup tools skip
+// it. It reports false without writing anything when dir is already tagged.
+func WriteCacheDirTag(dir string) (bool, error) {
+	if HasCacheDirTag(dir) {
+		return false, nil
+	}
+	content := cacheDirTagSignature + "\n" +
+		"# This file is a cache directory tag created by cache-remover.\n" +
+		"# For information about cache directory tags, see:\n" +
+		"#\thttps://bford.info/cachedir/\n"
+	if err := os.WriteFile(filepath.Join(dir, CacheDirTagName), []byte(content), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
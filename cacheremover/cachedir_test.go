@@ -0,0 +1,68 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheDirTag(t *testing.T) {
+	dir := t.TempDir()
+	if HasCacheDirTag(dir) {
+		t.Fatal("Empty directory should not be tagged")
+	}
+
+	// A file with the right name but no signature is not a valid tag
+	os.WriteFile(filepath.Join(dir, CacheDirTagName), []byte("not a tag\n"), 0644)
+	if HasCacheDirTag(dir) {
+		t.Error("Tag without signature should be rejected")
+	}
+	os.Remove(filepath.Join(dir, CacheDirTagName))
+
+	written, err := WriteCacheDirTag(dir)
+	if err != nil || !written {
+		t.Fatalf("WriteCacheDirTag = %v, %v", written, err)
+	}
+	if !HasCacheDirTag(dir) {
+		t.Error("Written tag should be valid")
+	}
+	if written, _ := WriteCacheDirTag(dir); written {
+		t.Error("An existing tag should not be rewritten")
+	}
+}
+
+func TestTaggedDirectoriesAreCaches(t *testing.T) {
+	scanner := newTestScanner()
+	root := t.TempDir()
+	project := filepath.Join(root, "app")
+	tagged := filepath.Join(project, "tool-output")
+	nested := filepath.Join(tagged, "sub")
+
+	os.MkdirAll(nested, 0755)
+	os.WriteFile(filepath.Join(project, "go.mod"), []byte("module app\n"), 0644)
+	os.WriteFile(filepath.Join(nested, "go.mod"), []byte("module sub\n"), 0644)
+	os.WriteFile(filepath.Join(nested, "data.bin"), []byte("cached"), 0644)
+	if _, err := WriteCacheDirTag(tagged); err != nil {
+		t.Fatal(err)
+	}
+
+	// The project inside the tagged directory is not discovered
+	projects, err := scanner.FindProjects(context.Background(), root)
+	if err != nil {
+		t.Fatalf("FindProjects failed: %v", err)
+	}
+	if len(projects) != 1 || projects[0] != project {
+		t.Errorf("Expected only %s, got %v", project, projects)
+	}
+
+	// The configuration does not declare tool-output as a cache, but the tag does
+	config := CacheConfig{Directories: []string{"vendor"}}
+	items, err := scanner.FindCacheItems(context.Background(), project, config)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Path != tagged {
+		t.Errorf("Expected the tagged directory as the only item, got %v", items)
+	}
+}
@@ -0,0 +1,192 @@
+package cacheremover
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type CleanupStats struct {
+	TotalProjects   int `json:"total_projects"`
+	TotalCacheItems int `json:"total_cache_items"`
+	// TotalSizeRemoved is the disk space freed; TotalApparentSize is the
+	// apparent size of what was removed. See CacheItem.
+	TotalSizeRemoved  int64         `json:"total_size_removed"`
+	TotalApparentSize int64         `json:"total_apparent_size"`
+	ProcessingTime    time.Duration `json:"processing_time_ns"`
+	mu                sync.Mutex
+}
+
+func (s *CleanupStats) Add(items int, size, apparent int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TotalCacheItems += items
+	s.TotalSizeRemoved += size
+	s.TotalApparentSize += apparent
+}
+
+func (s *CleanupStats) IncrementProjects() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TotalProjects++
+}
+
+// RemoveFailure records a cache item that could not be removed
+type RemoveFailure struct {
+	Item CacheItem
+	Err  error
+}
+
+// CleanResult is the outcome of removing a set of cache items
+type CleanResult struct {
+	Removed []CacheItem
+	Failed  []RemoveFailure
+	// Skipped lists the items that were not attempted because the clean
+	// was cancelled
+	Skipped []CacheItem
+	// BytesRemoved is the disk space actually freed, including by items
+	// that could only be removed partially
+	BytesRemoved int64
+	// ApparentBytesRemoved is the apparent size of the removed items
+	ApparentBytesRemoved int64
+	// Trashed is true when items were moved to the trash instead of deleted
+	Trashed bool
+	// Commands are the clean commands that were run, in order
+	Commands []CommandRun
+}
+
+// CleanProgress reports a cache item a Cleaner is about to remove or has
+// just removed
+type CleanProgress struct {
+	Item CacheItem
+	// Done is false before the item is removed. Once it is true, BytesFreed
+	// and Err tell how the removal went.
+	Done       bool
+	BytesFreed int64
+	Err        error
+}
+
+// Cleaner removes cache items found by a Scanner
+type Cleaner struct {
+	config *Config
+
+	// Trash, if set, receives cache items instead of deleting them
+	Trash *Trash
+	// OnProgress, if set, is called before and after each cache item is
+	// removed. Projects cleaned in parallel call it from several goroutines.
+	OnProgress func(CleanProgress)
+}
+
+// NewCleaner creates a Cleaner for the given configuration
+func NewCleaner(config *Config) *Cleaner {
+	return &Cleaner{config: config}
+}
+
+// Clean removes each item in turn. Failures are recorded in the result and
+// do not stop the run; cancelling ctx lets the current item finish and
+// skips the others.
+func (c *Cleaner) Clean(ctx context.Context, items []CacheItem) (*CleanResult, error) {
+	return c.clean(ctx, items, "")
+}
+
+// CleanProject runs the clean commands of a scanned project and then
+// removes the cache items that are left. In trash mode no commands run,
+// since what they delete could not be restored. Cancelling ctx lets a
+// running command finish, like an item being removed.
+func (c *Cleaner) CleanProject(ctx context.Context, project *Project) (*CleanResult, error) {
+	var runs []CommandRun
+	if c.Trash == nil {
+		for _, planned := range project.CleanCommands() {
+			if ctx.Err() != nil {
+				break
+			}
+			run := runCleanCommand(ctx, planned)
+			runs = append(runs, run)
+			if run.Err == nil || run.Missing || planned.Command.mode() != CommandInstead {
+				continue
+			}
+
+			// The tool was meant to do the cleaning; don't second-guess it
+			result := &CleanResult{Commands: runs}
+			err := fmt.Errorf("clean command %q failed: %v", planned.Command.String(), run.Err)
+			for _, item := range project.Items {
+				result.Failed = append(result.Failed, RemoveFailure{Item: item, Err: err})
+			}
+			return result, ctx.Err()
+		}
+	}
+
+	result, err := c.clean(ctx, project.Items, project.Type.Name)
+	result.Commands = runs
+	return result, err
+}
+
+func (c *Cleaner) clean(ctx context.Context, items []CacheItem, projectType string) (*CleanResult, error) {
+	result := &CleanResult{Trashed: c.Trash != nil}
+
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			skip(result, items[i:])
+			return result, err
+		}
+
+		c.progress(CleanProgress{Item: item})
+		freed, err := c.removeItem(item, projectType)
+		c.progress(CleanProgress{Item: item, Done: true, BytesFreed: freed, Err: err})
+		result.BytesRemoved += freed
+		if err != nil {
+			result.Failed = append(result.Failed, RemoveFailure{Item: item, Err: err})
+			continue
+		}
+		result.Removed = append(result.Removed, item)
+		result.ApparentBytesRemoved += item.ApparentSize
+	}
+
+	return result, nil
+}
+
+// skip records the items a cancelled clean did not get to. Those a clean
+// command removed before the cancel count as removed.
+func skip(result *CleanResult, items []CacheItem) {
+	for _, item := range items {
+		if _, err := os.Lstat(item.Path); !os.IsNotExist(err) {
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+		result.Removed = append(result.Removed, item)
+		result.BytesRemoved += item.Size
+		result.ApparentBytesRemoved += item.ApparentSize
+	}
+}
+
+func (c *Cleaner) progress(progress CleanProgress) {
+	if c.OnProgress != nil {
+		c.OnProgress(progress)
+	}
+}
+
+// removeItem deletes or trashes a cache item and returns the bytes freed,
+// which may be non-zero even when parts of it could not be removed. An item
+// that is already gone, e.g. removed by a clean command, counts with the
+// size it had when it was scanned.
+func (c *Cleaner) removeItem(item CacheItem, projectType string) (int64, error) {
+	if _, err := os.Lstat(item.Path); os.IsNotExist(err) {
+		return item.Size, nil
+	}
+	if c.Trash != nil {
+		if _, err := c.Trash.Move(item, projectType); err != nil {
+			return 0, err
+		}
+		return item.Size, nil
+	}
+	report := RemoveAll(item.Path)
+	return report.BytesFreed, report.Err()
+}
+
+// Remove permanently deletes a cache file or directory, bypassing the
+// trash. A partial removal returns a *RemoveError.
+func (c *Cleaner) Remove(path string) error {
+	return RemoveAll(path).Err()
+}
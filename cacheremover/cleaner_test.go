@@ -0,0 +1,123 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestCleaner() *Cleaner {
+	config := DefaultConfig()
+	return NewCleaner(&config)
+}
+
+func TestCleanRemovesItems(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cacheDir := filepath.Join(tempDir, "node_modules")
+	os.MkdirAll(filepath.Join(cacheDir, "pkg"), 0755)
+	os.WriteFile(filepath.Join(cacheDir, "pkg", "index.js"), []byte("test"), 0644)
+
+	// The space freed is what the scan measured
+	size, apparent := dirSize(cacheDir)
+	item := CacheItem{Path: cacheDir, Size: size, ApparentSize: apparent, Type: "directory"}
+	result, err := newTestCleaner().Clean(context.Background(), []CacheItem{item})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if len(result.Removed) != 1 || result.BytesRemoved != size || result.ApparentBytesRemoved != 4 {
+		t.Errorf("Expected 1 item / %d bytes (4 apparent) removed, got %d / %d (%d)",
+			size, len(result.Removed), result.BytesRemoved, result.ApparentBytesRemoved)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Error("Cache directory should be removed")
+	}
+}
+
+func TestCleanMissingItem(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Removing something that is already gone counts as success
+	item := CacheItem{
+		Path: filepath.Join(tempDir, "test-file"),
+		Size: 100,
+		Type: "file",
+	}
+
+	result, err := newTestCleaner().Clean(context.Background(), []CacheItem{item})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+
+	if len(result.Removed) != 1 {
+		t.Errorf("Expected 1 item removed, got %d", len(result.Removed))
+	}
+
+	if result.BytesRemoved != 100 {
+		t.Errorf("Expected size 100 to be counted as removed, got %d", result.BytesRemoved)
+	}
+}
+
+func TestCleanCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "cache.pyc")
+	os.WriteFile(filePath, []byte("bytecode"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := newTestCleaner().Clean(ctx, []CacheItem{{Path: filePath, Size: 8, Type: "file"}})
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(result.Removed) != 0 {
+		t.Errorf("Expected nothing removed after cancellation, got %d", len(result.Removed))
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		t.Error("File should still exist after cancelled clean")
+	}
+}
+
+func TestCleanCancelledFinishesCurrentItem(t *testing.T) {
+	tempDir := t.TempDir()
+	var items []CacheItem
+	for _, name := range []string{"a.pyc", "b.pyc", "c.pyc"} {
+		path := filepath.Join(tempDir, name)
+		os.WriteFile(path, []byte("bytecode"), 0644)
+		items = append(items, CacheItem{Path: path, Size: 8, Type: "file"})
+	}
+
+	// Cancel while the second item is being removed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cleaner := newTestCleaner()
+	var events []string
+	cleaner.OnProgress = func(progress CleanProgress) {
+		name := filepath.Base(progress.Item.Path)
+		if progress.Done {
+			events = append(events, "done "+name)
+			return
+		}
+		events = append(events, "start "+name)
+		if name == "b.pyc" {
+			cancel()
+		}
+	}
+
+	result, err := cleaner.Clean(ctx, items)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(result.Removed) != 2 || len(result.Skipped) != 1 || result.Skipped[0].Path != items[2].Path {
+		t.Errorf("Expected a and b removed and c skipped, got %+v", result)
+	}
+	if got := strings.Join(events, ", "); got != "start a.pyc, done a.pyc, start b.pyc, done b.pyc" {
+		t.Errorf("Unexpected progress events: %s", got)
+	}
+	if _, err := os.Stat(items[2].Path); err != nil {
+		t.Error("The skipped file should still exist")
+	}
+}
@@ -0,0 +1,158 @@
+package cacheremover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes of a clean command
+const (
+	// CommandBefore runs the command, such as "gradle --stop", and then
+	// removes the cache items as usual, even when the command fails
+	CommandBefore = "before"
+	// CommandInstead lets the tool do the cleaning, such as "cargo clean".
+	// Items it leaves behind are removed as usual; when it fails, nothing
+	// is removed.
+	CommandInstead = "instead"
+)
+
+// defaultCommandTimeout bounds clean commands without timeout_seconds
+const defaultCommandTimeout = 2 * time.Minute
+
+// maxCommandOutput is how much of a command's output is kept, from the end
+const maxCommandOutput = 4096
+
+// CleanCommand is a tool command that cleans a project the way its
+// ecosystem intends, run in the project directory
+type CleanCommand struct {
+	// Command is the program and its arguments. The program is looked up
+	// on PATH; no shell is involved.
+	Command []string `json:"command"`
+	// Mode is CommandBefore (the default) or CommandInstead
+	Mode           string `json:"mode,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+// String returns the command line for display
+func (c *CleanCommand) String() string {
+	args := make([]string, len(c.Command))
+	for i, arg := range c.Command {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		args[i] = arg
+	}
+	return strings.Join(args, " ")
+}
+
+func (c *CleanCommand) mode() string {
+	if c.Mode == "" {
+		return CommandBefore
+	}
+	return c.Mode
+}
+
+func (c *CleanCommand) timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return defaultCommandTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// validate checks the settings of a clean command
+func (c *CleanCommand) validate() error {
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return fmt.Errorf("clean command has no program")
+	}
+	switch c.mode() {
+	case CommandBefore, CommandInstead:
+	default:
+		return fmt.Errorf("clean command: unknown mode %q (use %q or %q)", c.Mode, CommandBefore, CommandInstead)
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("clean command: timeout_seconds cannot be negative")
+	}
+	return nil
+}
+
+// PlannedCommand is a clean command and the directory it runs in
+type PlannedCommand struct {
+	Dir     string
+	Command *CleanCommand
+}
+
+// CommandRun is the outcome of running a clean command
+type CommandRun struct {
+	PlannedCommand
+	// Missing is true when the program is not installed; nothing ran
+	Missing bool
+	// Output is the end of the combined stdout and stderr
+	Output   string
+	Err      error
+	Duration time.Duration
+}
+
+// CleanCommands returns the clean commands of the project's types and of
+// its workspace members. Identical command lines run only once, in the
+// outermost project, since tools like cargo clean the whole workspace.
+func (p *Project) CleanCommands() []PlannedCommand {
+	var planned []PlannedCommand
+	seen := make(map[string]bool)
+	var collect func(project *Project)
+	collect = func(project *Project) {
+		for _, pt := range project.Types {
+			if pt.CleanCommand == nil || seen[pt.CleanCommand.String()] {
+				continue
+			}
+			seen[pt.CleanCommand.String()] = true
+			planned = append(planned, PlannedCommand{Dir: project.Path, Command: pt.CleanCommand})
+		}
+		for _, member := range project.Members {
+			collect(member)
+		}
+	}
+	collect(p)
+	return planned
+}
+
+// runCleanCommand runs a clean command, killing it when it exceeds its
+// timeout. Cancelling ctx does not stop it: like an item being removed, a
+// command that has started is left to finish.
+func runCleanCommand(ctx context.Context, planned PlannedCommand) CommandRun {
+	run := CommandRun{PlannedCommand: planned}
+	program, err := exec.LookPath(planned.Command.Command[0])
+	if err != nil {
+		run.Missing = errors.Is(err, exec.ErrNotFound)
+		run.Err = err
+		return run
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), planned.Command.timeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, program, planned.Command.Command[1:]...)
+	cmd.Dir = planned.Dir
+	// Don't wait forever for children that keep the output open
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	run.Duration = time.Since(start)
+	if len(output) > maxCommandOutput {
+		output = output[len(output)-maxCommandOutput:]
+	}
+	run.Output = strings.TrimSpace(string(output))
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		run.Err = fmt.Errorf("timed out after %v", planned.Command.timeout())
+	case err != nil:
+		run.Err = err
+	}
+	return run
+}
@@ -0,0 +1,197 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubCommand installs an executable shell script on PATH for the test
+func stubCommand(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub commands are shell scripts")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// scanStubProject scans a project of a type that uses command to clean
+// its "out" and "tmp" directories
+func scanStubProject(t *testing.T, command *CleanCommand) *Project {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"stub.toml":   "",
+		"out/app.bin": "binary",
+		"tmp/log.txt": "log",
+	})
+
+	config := Config{ProjectTypes: []ProjectType{{
+		Name:         "Stub",
+		Indicators:   []string{"stub.toml"},
+		CacheConfig:  CacheConfig{Directories: []string{"out", "tmp"}},
+		CleanCommand: command,
+	}}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	project, err := NewScanner(&config).ScanProject(context.Background(), root)
+	if err != nil || project == nil || len(project.Items) != 2 {
+		t.Fatalf("Expected a project with 2 items, got %v, %v", project, err)
+	}
+	return project
+}
+
+func TestCleanCommandInstead(t *testing.T) {
+	stubCommand(t, "stubclean", `echo "cleaning $(basename "$PWD")"; rm -rf out`)
+	project := scanStubProject(t, &CleanCommand{Command: []string{"stubclean"}, Mode: CommandInstead})
+
+	result, err := newTestCleaner().CleanProject(context.Background(), project)
+	if err != nil {
+		t.Fatalf("CleanProject failed: %v", err)
+	}
+	if len(result.Commands) != 1 || result.Commands[0].Err != nil {
+		t.Fatalf("Expected one successful command, got %+v", result.Commands)
+	}
+	if want := "cleaning " + filepath.Base(project.Path); result.Commands[0].Output != want {
+		t.Errorf("Expected output %q from the project directory, got %q", want, result.Commands[0].Output)
+	}
+
+	// What the tool removed counts as removed, what it left is removed as usual
+	if len(result.Removed) != 2 || result.BytesRemoved != project.TotalSize {
+		t.Errorf("Expected both items removed, got %v", result.Removed)
+	}
+	for _, item := range project.Items {
+		if _, err := os.Stat(item.Path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", item.Path)
+		}
+	}
+}
+
+func TestCleanCommandInsteadFailure(t *testing.T) {
+	stubCommand(t, "stubclean", `echo "lock held" >&2; exit 3`)
+	project := scanStubProject(t, &CleanCommand{Command: []string{"stubclean"}, Mode: CommandInstead})
+
+	result, _ := newTestCleaner().CleanProject(context.Background(), project)
+	if len(result.Commands) != 1 || result.Commands[0].Err == nil || result.Commands[0].Output != "lock held" {
+		t.Fatalf("Expected the failure and its output, got %+v", result.Commands)
+	}
+	if len(result.Removed) != 0 || len(result.Failed) != 2 {
+		t.Errorf("Expected both items to fail, got %d removed and %d failed", len(result.Removed), len(result.Failed))
+	}
+	for _, item := range project.Items {
+		if _, err := os.Stat(item.Path); err != nil {
+			t.Errorf("%s should be kept: %v", item.Path, err)
+		}
+	}
+}
+
+func TestCleanCommandBefore(t *testing.T) {
+	stubCommand(t, "stubstop", `exit 1`)
+	project := scanStubProject(t, &CleanCommand{Command: []string{"stubstop", "--stop"}})
+
+	// A failing command does not keep the items
+	result, _ := newTestCleaner().CleanProject(context.Background(), project)
+	if len(result.Commands) != 1 || result.Commands[0].Err == nil {
+		t.Fatalf("Expected a failed command, got %+v", result.Commands)
+	}
+	if len(result.Removed) != 2 {
+		t.Errorf("Expected both items removed, got %v", result.Removed)
+	}
+}
+
+func TestCleanCommandMissing(t *testing.T) {
+	project := scanStubProject(t, &CleanCommand{Command: []string{"cache-remover-no-such-tool"}, Mode: CommandInstead})
+
+	result, _ := newTestCleaner().CleanProject(context.Background(), project)
+	if len(result.Commands) != 1 || !result.Commands[0].Missing {
+		t.Fatalf("Expected a missing command, got %+v", result.Commands)
+	}
+	if len(result.Removed) != 2 {
+		t.Errorf("Expected items to be removed without the tool, got %v", result.Removed)
+	}
+}
+
+func TestCleanCommandTimeout(t *testing.T) {
+	stubCommand(t, "stubslow", `exec sleep 30`)
+	project := scanStubProject(t, &CleanCommand{Command: []string{"stubslow"}, Mode: CommandInstead, TimeoutSeconds: 1})
+
+	result, _ := newTestCleaner().CleanProject(context.Background(), project)
+	if len(result.Commands) != 1 || result.Commands[0].Err == nil || !strings.Contains(result.Commands[0].Err.Error(), "timed out") {
+		t.Fatalf("Expected a timeout, got %+v", result.Commands)
+	}
+	if len(result.Failed) != 2 {
+		t.Errorf("Expected items to be kept after a timeout, got %v", result.Removed)
+	}
+}
+
+func TestCleanCommandFinishesWhenCancelled(t *testing.T) {
+	stubCommand(t, "stubclean", `sleep 1; rm -rf out`)
+	project := scanStubProject(t, &CleanCommand{Command: []string{"stubclean"}, Mode: CommandInstead})
+
+	// Cancel while the command runs
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	result, err := newTestCleaner().CleanProject(ctx, project)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(result.Commands) != 1 || result.Commands[0].Err != nil {
+		t.Fatalf("Expected the command to finish, got %+v", result.Commands)
+	}
+
+	// What the command removed counts as removed, the rest is left alone
+	if len(result.Removed) != 1 || filepath.Base(result.Removed[0].Path) != "out" || len(result.Failed) != 0 {
+		t.Errorf("Expected out removed by the command, got %+v", result)
+	}
+	if len(result.Skipped) != 1 || filepath.Base(result.Skipped[0].Path) != "tmp" {
+		t.Fatalf("Expected tmp skipped, got %v", result.Skipped)
+	}
+	if _, err := os.Stat(result.Skipped[0].Path); err != nil {
+		t.Errorf("The skipped item should still exist: %v", err)
+	}
+}
+
+func TestCleanCommandsOncePerWorkspace(t *testing.T) {
+	cargo := &ProjectType{Name: "Rust", CleanCommand: &CleanCommand{Command: []string{"cargo", "clean"}}}
+	node := &ProjectType{Name: "Node.js", CleanCommand: &CleanCommand{Command: []string{"npm", "cache", "clean", "--force"}}}
+	project := &Project{
+		Path:  "/ws",
+		Types: []*ProjectType{cargo},
+		Members: []*Project{
+			{Path: "/ws/a", Types: []*ProjectType{cargo}},
+			{Path: "/ws/b", Types: []*ProjectType{cargo, node}},
+		},
+	}
+
+	var got []string
+	for _, planned := range project.CleanCommands() {
+		got = append(got, planned.Dir+": "+planned.Command.String())
+	}
+	want := []string{"/ws: cargo clean", "/ws/b: npm cache clean --force"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CleanCommands() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateRejectsBadCleanCommand(t *testing.T) {
+	for _, command := range []*CleanCommand{
+		{},
+		{Command: []string{"cargo", "clean"}, Mode: "after"},
+		{Command: []string{"cargo", "clean"}, TimeoutSeconds: -1},
+	} {
+		config := DefaultConfig()
+		config.ProjectTypes[0].CleanCommand = command
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", command)
+		}
+	}
+}
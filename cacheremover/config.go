@@ -0,0 +1,398 @@
+package cacheremover
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type CacheConfig struct {
+	Directories []string `json:"directories"`
+	Files       []string `json:"files"`
+	Extensions  []string `json:"extensions"`
+	// Exclude lists patterns for paths that are never removed, even when
+	// they match one of the patterns above
+	Exclude []string `json:"exclude,omitempty"`
+	// Signatures maps an entry of Directories to patterns of which at least
+	// one must exist inside a matching directory, such as "pyvenv.cfg" for a
+	// virtual environment. Directories without an entry are not verified.
+	Signatures map[string][]string `json:"signatures,omitempty"`
+}
+
+type ProjectType struct {
+	Name        string      `json:"name"`
+	Indicators  []string    `json:"indicators"`
+	CacheConfig CacheConfig `json:"cache_config"`
+	// CleanCommand, if set, runs the ecosystem's own cleanup in the
+	// project directory before or instead of removing the cache items
+	CleanCommand *CleanCommand `json:"clean_command,omitempty"`
+}
+
+// CombineProjectTypes merges the project types detected in one directory.
+// A single type is returned unchanged and no types yield nil. Otherwise the
+// result is named after all of them, e.g. "Node.js+Python", and its
+// indicators and cache patterns are the union of theirs without duplicates.
+func CombineProjectTypes(types []*ProjectType) *ProjectType {
+	switch len(types) {
+	case 0:
+		return nil
+	case 1:
+		return types[0]
+	}
+
+	var names []string
+	combined := &ProjectType{}
+	for _, pt := range types {
+		names = append(names, pt.Name)
+		combined.Indicators = appendUnique(combined.Indicators, pt.Indicators)
+		combined.CacheConfig.Directories = appendUnique(combined.CacheConfig.Directories, pt.CacheConfig.Directories)
+		combined.CacheConfig.Files = appendUnique(combined.CacheConfig.Files, pt.CacheConfig.Files)
+		combined.CacheConfig.Extensions = appendUnique(combined.CacheConfig.Extensions, pt.CacheConfig.Extensions)
+		combined.CacheConfig.Exclude = appendUnique(combined.CacheConfig.Exclude, pt.CacheConfig.Exclude)
+	}
+	combined.Name = strings.Join(names, "+")
+	combined.CacheConfig.Signatures = combineSignatures(types)
+	return combined
+}
+
+// combineSignatures merges the signatures of several project types. A
+// directory that one type removes without verification is not verified in
+// the combination either.
+func combineSignatures(types []*ProjectType) map[string][]string {
+	signatures := make(map[string][]string)
+	unverified := make(map[string]bool)
+	for _, pt := range types {
+		for _, dir := range pt.CacheConfig.Directories {
+			markers, ok := pt.CacheConfig.Signatures[dir]
+			if !ok {
+				unverified[dir] = true
+				continue
+			}
+			signatures[dir] = appendUnique(signatures[dir], markers)
+		}
+	}
+	for dir := range unverified {
+		delete(signatures, dir)
+	}
+	if len(signatures) == 0 {
+		return nil
+	}
+	return signatures
+}
+
+func appendUnique(dst, src []string) []string {
+	for _, s := range src {
+		if !contains(dst, s) {
+			dst = append(dst, s)
+		}
+	}
+	return dst
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type Config struct {
+	ProjectTypes []ProjectType `json:"project_types"`
+	// GlobalCaches are the toolchain caches outside projects that --global
+	// sizes and prunes. Leaving the section out uses the defaults; an empty
+	// list disables them.
+	GlobalCaches []GlobalCache `json:"global_caches,omitempty"`
+	Settings     Settings      `json:"settings"`
+}
+
+type Settings struct {
+	MaxDepth       int    `json:"max_depth"`
+	DefaultWorkers int    `json:"default_workers"`
+	LogLevel       string `json:"log_level"`
+	// TrashDir is where --trash moves cache items; empty means DefaultTrashDir()
+	TrashDir string `json:"trash_dir,omitempty"`
+	// HistoryFile is where cleanup runs are logged; empty means DefaultHistoryPath()
+	HistoryFile string `json:"history_file,omitempty"`
+	// IndexDir holds the scan index; empty means DefaultIndexDir()
+	IndexDir string `json:"index_dir,omitempty"`
+}
+
+// LoadConfigFile reads and validates a JSON configuration file
+func LoadConfigFile(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid JSON in config file %s: %v", configPath, err)
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %v", configPath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate checks the configuration and fills in defaults for unset settings
+func (config *Config) Validate() error {
+	if len(config.ProjectTypes) == 0 {
+		return fmt.Errorf("no project types defined")
+	}
+
+	for i, pt := range config.ProjectTypes {
+		if pt.Name == "" {
+			return fmt.Errorf("project type %d has empty name", i)
+		}
+		if len(pt.Indicators) == 0 {
+			return fmt.Errorf("project type '%s' has no indicators", pt.Name)
+		}
+		if err := validatePatterns(pt); err != nil {
+			return fmt.Errorf("project type '%s': %v", pt.Name, err)
+		}
+		if pt.CleanCommand != nil {
+			if err := pt.CleanCommand.validate(); err != nil {
+				return fmt.Errorf("project type '%s': %v", pt.Name, err)
+			}
+		}
+	}
+
+	if config.GlobalCaches == nil {
+		config.GlobalCaches = DefaultConfig().GlobalCaches
+	}
+	for _, cache := range config.GlobalCaches {
+		if err := cache.validate(); err != nil {
+			return err
+		}
+	}
+
+	if config.Settings.MaxDepth <= 0 {
+		config.Settings.MaxDepth = 10
+	}
+	if config.Settings.DefaultWorkers <= 0 {
+		config.Settings.DefaultWorkers = 4
+	}
+	if config.Settings.LogLevel == "" {
+		config.Settings.LogLevel = "info"
+	}
+
+	return nil
+}
+
+// validatePatterns compiles every pattern of a project type. Indicators and
+// signatures are checked for every candidate directory, so they may not use
+// "**" or regular expressions, which would require walking whole subtrees.
+func validatePatterns(pt ProjectType) error {
+	for _, indicator := range pt.Indicators {
+		if err := validateMarker(indicator); err != nil {
+			return fmt.Errorf("indicator: %v", err)
+		}
+	}
+	for _, dir := range pt.CacheConfig.Directories {
+		if _, err := compilePattern(dir); err != nil {
+			return fmt.Errorf("cache directory: %v", err)
+		}
+	}
+	for _, file := range pt.CacheConfig.Files {
+		if _, err := compilePattern(file); err != nil {
+			return fmt.Errorf("cache file: %v", err)
+		}
+	}
+	for _, exclude := range pt.CacheConfig.Exclude {
+		if _, err := compilePattern(exclude); err != nil {
+			return fmt.Errorf("exclude: %v", err)
+		}
+	}
+	for dir, markers := range pt.CacheConfig.Signatures {
+		if !contains(pt.CacheConfig.Directories, dir) {
+			return fmt.Errorf("signature for %q, which is not a cache directory", dir)
+		}
+		for _, marker := range markers {
+			if err := validateMarker(marker); err != nil {
+				return fmt.Errorf("signature of %q: %v", dir, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateMarker checks a pattern whose existence is tested inside a
+// directory, such as an indicator or a signature
+func validateMarker(raw string) error {
+	p, err := compilePattern(raw)
+	if err != nil {
+		return err
+	}
+	if p.re != nil || strings.Contains(raw, "**") {
+		return fmt.Errorf("%q: only *, ? and [...] wildcards are supported", raw)
+	}
+	return nil
+}
+
+// virtualenvNames are the Python cache directories that hold virtual
+// environments. Names like "env" or "testing" are common for source folders
+// too, so they are only removed when they contain an environment.
+var virtualenvNames = []string{
+	"venv", ".venv", "env", ".env", "virtualenv", ".virtualenv", "conda-env",
+	"venv3", "venv2", "python-env", "py3env", "ENV",
+	"myenv", "dev-env", "test-env", "prod-env", "local-env",
+	"development", "testing", "ml-env", "mlenv", "data-env",
+	"poetry-env", "pipenv-env", "codebase-analyzer-env",
+}
+
+// DefaultConfig returns the built-in project types and settings
+func DefaultConfig() Config {
+	pythonSignatures := map[string][]string{
+		// Directories holding several environments
+		"venvs":  {"*/pyvenv.cfg", "*/conda-meta"},
+		".venvs": {"*/pyvenv.cfg", "*/conda-meta"},
+		"envs":   {"*/pyvenv.cfg", "*/conda-meta"},
+		".envs":  {"*/pyvenv.cfg", "*/conda-meta"},
+	}
+	for _, name := range virtualenvNames {
+		// pyvenv.cfg for venv and virtualenv 20+, conda-meta for conda and
+		// activation scripts for older virtualenv releases
+		pythonSignatures[name] = []string{"pyvenv.cfg", "conda-meta", "bin/activate", "Scripts/activate.bat"}
+	}
+	// The lock files npm 7+, pnpm and yarn write into node_modules, or any
+	// installed package for other package managers
+	nodeSignatures := map[string][]string{
+		"node_modules": {".package-lock.json", ".modules.yaml", ".yarn-integrity", ".yarn-state.yml", "*/package.json", "@*/*/package.json"},
+	}
+
+	return Config{
+		ProjectTypes: []ProjectType{
+			{
+				Name:       "Node.js",
+				Indicators: []string{"package.json", "yarn.lock", "package-lock.json", "pnpm-workspace.yaml"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"node_modules", "dist", "build", ".next", ".nuxt", "coverage"},
+					Files:       []string{},
+					Extensions:  []string{},
+					Signatures:  nodeSignatures,
+				},
+			},
+			{
+				Name:       "Python",
+				Indicators: []string{"requirements.txt", "setup.py", "pyproject.toml", "Pipfile"},
+				CacheConfig: CacheConfig{
+					Directories: []string{
+						// Cache directories
+						"__pycache__", ".pytest_cache", "dist", "build", ".mypy_cache", ".tox",
+						// Virtual environments (completely re-installable)
+						"venv", ".venv", "env", ".env", "virtualenv", ".virtualenv",
+						"venv3", "venv2", "python-env", "pyenv", ".pyenv", "py3env",
+						"ENV", "venvs", ".venvs", "envs", ".envs",
+						// Conda/Anaconda environments
+						"conda", "conda-env", "miniconda", "anaconda",
+						// Common project-specific names
+						"myenv", "dev-env", "test-env", "prod-env", "local-env",
+						"development", "testing", "ml-env", "mlenv", "data-env",
+						// Poetry environments
+						".poetry", "poetry-env",
+						// Pipenv
+						".pipenv", "pipenv-env", "codebase-analyzer-env",
+					},
+					Files:      []string{},
+					Extensions: []string{".pyc", ".pyo"},
+					Signatures: pythonSignatures,
+				},
+			},
+			{
+				Name:       "Java/Maven",
+				Indicators: []string{"pom.xml"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"target"},
+					Files:       []string{},
+					Extensions:  []string{},
+				},
+			},
+			{
+				Name:       "Gradle",
+				Indicators: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"build", ".gradle"},
+					Files:       []string{},
+					Extensions:  []string{},
+				},
+			},
+			{
+				Name:       "Go",
+				Indicators: []string{"go.mod", "go.sum", "go.work"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"vendor"},
+					Files:       []string{},
+					Extensions:  []string{},
+				},
+			},
+			{
+				Name:       "Rust",
+				Indicators: []string{"Cargo.toml"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"target"},
+					Files:       []string{},
+					Extensions:  []string{},
+					Signatures: map[string][]string{
+						"target": {"CACHEDIR.TAG", ".rustc_info.json"},
+					},
+				},
+			},
+			{
+				Name:       "Angular",
+				Indicators: []string{"angular.json"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"node_modules", "dist", ".angular"},
+					Files:       []string{},
+					Extensions:  []string{},
+					Signatures:  nodeSignatures,
+				},
+			},
+			{
+				Name:       "Flutter",
+				Indicators: []string{"pubspec.yaml"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"build", ".dart_tool"},
+					Files:       []string{},
+					Extensions:  []string{},
+				},
+			},
+			{
+				Name:       "Swift/iOS",
+				Indicators: []string{"Package.swift", "*.xcodeproj", "*.xcworkspace"},
+				CacheConfig: CacheConfig{
+					Directories: []string{"build", "DerivedData", ".build"},
+					Files:       []string{},
+					Extensions:  []string{},
+				},
+			},
+		},
+		GlobalCaches: []GlobalCache{
+			// Files Maven has not resolved for three months; a missing jar
+			// is downloaded again on the next build
+			{Name: "Maven repository", Path: "~/.m2/repository", Strategy: GlobalStrategyUnused, UnusedDays: 90},
+			// group/artifact/version directories of the Gradle module cache
+			{Name: "Gradle caches", Path: "~/.gradle/caches/modules-2/files-2.1", Strategy: GlobalStrategyUnused, UnusedDays: 90, EntryDepth: 3},
+			{Name: "npm cache", Path: "~/.npm/_cacache", Strategy: GlobalStrategyUnused, UnusedDays: 30},
+			{Name: "pip cache", Path: "{user_cache}/pip", Strategy: GlobalStrategyUnused, UnusedDays: 30},
+			// Downloaded crates, extracted sources and index caches, each as a unit
+			{Name: "Cargo registry", Path: "~/.cargo/registry", Strategy: GlobalStrategyUnused, UnusedDays: 90, EntryDepth: 3},
+			// Module sources cannot be pruned piecemeal, so the module cache
+			// is only removed once Go has not been used for a while
+			{Name: "Go modules", Path: "~/go/pkg/mod", Strategy: GlobalStrategyAll, UnusedDays: 90},
+			{Name: "Go build cache", Path: "{user_cache}/go-build", Strategy: GlobalStrategyUnused, UnusedDays: 30},
+			// package-version directories below each hosted repository
+			{Name: "Dart/Flutter pub cache", Path: "~/.pub-cache/hosted", Strategy: GlobalStrategyUnused, UnusedDays: 90, EntryDepth: 2},
+		},
+		Settings: Settings{
+			MaxDepth:       10,
+			DefaultWorkers: 4,
+			LogLevel:       "info",
+		},
+	}
+}
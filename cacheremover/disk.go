@@ -0,0 +1,17 @@
+package cacheremover
+
+// DiskUsage describes the capacity of the filesystem holding a path
+type DiskUsage struct {
+	// Total is the size of the filesystem in bytes
+	Total uint64
+	// Available is the space unprivileged users can still write, in bytes
+	Available uint64
+}
+
+// AvailablePercent returns Available as a percentage of Total
+func (u DiskUsage) AvailablePercent() float64 {
+	if u.Total == 0 {
+		return 0
+	}
+	return float64(u.Available) / float64(u.Total) * 100
+}
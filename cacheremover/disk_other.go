@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || dragonfly || windows)
+
+package cacheremover
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// DiskUsageOf is not supported on this platform
+func DiskUsageOf(path string) (DiskUsage, error) {
+	return DiskUsage{}, fmt.Errorf("disk usage is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package cacheremover
+
+import "syscall"
+
+// DiskUsageOf reports the capacity of the filesystem holding path
+func DiskUsageOf(path string) (DiskUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return DiskUsage{}, err
+	}
+	blockSize := uint64(stat.Bsize)
+	return DiskUsage{
+		Total:     uint64(stat.Blocks) * blockSize,
+		Available: uint64(stat.Bavail) * blockSize,
+	}, nil
+}
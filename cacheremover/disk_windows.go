@@ -0,0 +1,30 @@
+//go:build windows
+
+package cacheremover
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// DiskUsageOf reports the capacity of the volume holding path
+func DiskUsageOf(path string) (DiskUsage, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+
+	var available, total, free uint64
+	ok, _, err := procGetDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)),
+	)
+	if ok == 0 {
+		return DiskUsage{}, err
+	}
+	return DiskUsage{Total: total, Available: available}, nil
+}
@@ -0,0 +1,92 @@
+package cacheremover
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the gitignore-style file that protects paths from
+// cleanup. It is honoured in a project directory and in every directory
+// above it, so one file at the scan root can cover a whole tree.
+const IgnoreFileName = ".cacheremoverignore"
+
+// isExcludedDir reports whether a directory is protected by a
+// .cacheremoverignore file, in which case FindProjects does not descend into it
+func (s *Scanner) isExcludedDir(dirPath string) bool {
+	return s.ignoredByFile(dirPath, true)
+}
+
+// checkExcluded drops items matched by the project type's exclude patterns
+// or by a .cacheremoverignore file. An item is also kept when one of its
+// parent directories inside the project is excluded.
+func (s *Scanner) checkExcluded(projectPath string, config CacheConfig, items []CacheItem) []CacheItem {
+	exclude := compilePatterns(config.Exclude)
+
+	kept := items[:0]
+	for _, item := range items {
+		if s.isExcluded(projectPath, item.Path, item.Type == "directory", exclude) {
+			s.emit(EventExcluded, item.Path, nil)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+func (s *Scanner) isExcluded(projectPath, itemPath string, isDir bool, exclude []*pattern) bool {
+	if s.ignoredByFile(projectPath, true) {
+		return true
+	}
+
+	rel := relSlash(projectPath, itemPath)
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		prefix := strings.Join(parts[:i], "/")
+		for _, p := range exclude {
+			if p.isPath() && p.matchPath(prefix) || !p.isPath() && p.matchName(parts[i-1]) {
+				return true
+			}
+		}
+		if s.ignoredByFile(filepath.Join(projectPath, filepath.FromSlash(prefix)), i < len(parts) || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredByFile applies the .cacheremoverignore files above target. Files
+// closer to target take precedence over those further up.
+func (s *Scanner) ignoredByFile(target string, isDir bool) bool {
+	dir := filepath.Dir(target)
+	for {
+		if ignored, matched := s.ignoreFile(dir).match(relSlash(dir, target), isDir); matched {
+			return ignored
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// ignoreFile returns the rules of the .cacheremoverignore file in dir.
+// Files are read once per scan.
+func (s *Scanner) ignoreFile(dir string) *ignoreRules {
+	s.ignoreMu.Lock()
+	defer s.ignoreMu.Unlock()
+
+	if rules, ok := s.ignoreFiles[dir]; ok {
+		return rules
+	}
+	if s.ignoreFiles == nil {
+		s.ignoreFiles = make(map[string]*ignoreRules)
+	}
+	rules, err := readIgnoreFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		s.emit(EventAccessError, filepath.Join(dir, IgnoreFileName), err)
+		rules = &ignoreRules{}
+	}
+	s.ignoreFiles[dir] = rules
+	return rules
+}
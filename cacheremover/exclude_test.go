@@ -0,0 +1,85 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExcludePatterns(t *testing.T) {
+	scanner := newTestScanner()
+	root := t.TempDir()
+	for _, dir := range []string{"node_modules", "dist", "packages/ui/dist", "vendor/tools/dist"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+		os.WriteFile(filepath.Join(root, dir, "index.js"), []byte("x"), 0644)
+	}
+
+	config := CacheConfig{
+		Directories: []string{"node_modules", "dist"},
+		Exclude:     []string{"packages/*/dist", "vendor"},
+	}
+	items, err := scanner.FindCacheItems(context.Background(), root, config)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+	if got, want := itemPaths(root, items), "dist,node_modules"; got != want {
+		t.Errorf("Expected items %s, got %s", want, got)
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	scanner := newTestScanner()
+	root := t.TempDir()
+
+	// A library that commits its build output, and a project protected from the scan root
+	for _, project := range []string{"lib", "app", "legacy"} {
+		os.MkdirAll(filepath.Join(root, project, "node_modules"), 0755)
+		os.MkdirAll(filepath.Join(root, project, "dist"), 0755)
+		os.WriteFile(filepath.Join(root, project, "package.json"), []byte("{}"), 0644)
+		os.WriteFile(filepath.Join(root, project, "node_modules", ".package-lock.json"), []byte("x"), 0644)
+		os.WriteFile(filepath.Join(root, project, "dist", "a.js"), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("legacy/\n"), 0644)
+	os.WriteFile(filepath.Join(root, "lib", IgnoreFileName), []byte("# committed artifacts\ndist/\n"), 0644)
+
+	var excluded []string
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventExcluded {
+			excluded = append(excluded, relSlash(root, event.Path))
+		}
+	}
+
+	projects, err := scanner.Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var found []string
+	for _, project := range projects {
+		for _, item := range project.Items {
+			found = append(found, relSlash(root, item.Path))
+		}
+	}
+	sort.Strings(found)
+	want := "app/dist,app/node_modules,lib/node_modules"
+	if got := strings.Join(found, ","); got != want {
+		t.Errorf("Expected items %s, got %s", want, got)
+	}
+
+	sort.Strings(excluded)
+	if got := strings.Join(excluded, ","); got != "legacy,lib/dist" {
+		t.Errorf("Expected exclusion events for legacy and lib/dist, got %s", got)
+	}
+}
+
+func itemPaths(root string, items []CacheItem) string {
+	var paths []string
+	for _, item := range items {
+		paths = append(paths, relSlash(root, item.Path))
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
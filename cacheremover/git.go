@@ -0,0 +1,327 @@
+package cacheremover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Git status of a cache item, as reported in CacheItem.GitStatus. Items
+// outside a git work tree have an empty status.
+const (
+	// GitIgnored means the item is excluded by .gitignore or .git/info/exclude
+	GitIgnored = "ignored"
+	// GitUntracked means the item is neither ignored nor committed
+	GitUntracked = "untracked"
+	// GitTracked means the item is, or contains, a file in the git index
+	GitTracked = "tracked"
+)
+
+// gitRepo answers status questions about paths in one git work tree. It
+// reads .git/index and the ignore files directly, without the git binary.
+type gitRepo struct {
+	root      string // Work tree root
+	gitDir    string // .git directory, or the directory a .git file points to
+	commonDir string // Shared directory of linked worktrees; equal to gitDir otherwise
+
+	mu         sync.Mutex
+	tracked    []indexEntry // Sorted by path
+	indexMod   time.Time
+	indexSize  int64
+	exclude    *ignoreRules
+	gitignores map[string]*ignoreRules // Keyed by slash-separated directory relative to root
+	info       *RepoInfo               // Computed on first use
+}
+
+// indexEntry is the part of a git index entry needed to answer status questions
+type indexEntry struct {
+	path      string // Slash-separated, relative to the work tree root
+	mode      uint32
+	mtime     int64  // Seconds
+	size      uint32 // File size truncated to 32 bits, as git stores it
+	skipCheck bool   // assume-unchanged or skip-worktree: the work tree copy is not compared
+}
+
+// findGitRepo looks for the work tree containing dir. It returns nil when
+// dir is not inside a git repository.
+func findGitRepo(dir string) *gitRepo {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+
+	for {
+		if gitDir, ok := resolveGitDir(filepath.Join(dir, ".git")); ok {
+			commonDir := gitDir
+			if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+				commonDir = strings.TrimSpace(string(data))
+				if !filepath.IsAbs(commonDir) {
+					commonDir = filepath.Join(gitDir, commonDir)
+				}
+			}
+			return &gitRepo{root: dir, gitDir: gitDir, commonDir: filepath.Clean(commonDir)}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// resolveGitDir returns the git directory for a .git entry, following the
+// "gitdir:" file used by linked worktrees and submodules
+func resolveGitDir(dotGit string) (string, bool) {
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", false
+	}
+	if info.IsDir() {
+		return dotGit, true
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", false
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", false
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(dotGit), gitDir)
+	}
+	return filepath.Clean(gitDir), true
+}
+
+// refresh (re)loads the index and ignore files when the index has changed
+// since they were last read
+func (r *gitRepo) refresh() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	indexPath := filepath.Join(r.gitDir, "index")
+	var mod time.Time
+	var size int64
+	if info, err := os.Stat(indexPath); err == nil {
+		mod, size = info.ModTime(), info.Size()
+	}
+	if r.exclude != nil && mod.Equal(r.indexMod) && size == r.indexSize {
+		return nil
+	}
+
+	tracked, err := readGitIndex(indexPath)
+	if err != nil {
+		return err
+	}
+	exclude, err := readIgnoreFile(filepath.Join(r.commonDir, "info", "exclude"))
+	if err != nil {
+		exclude = &ignoreRules{}
+	}
+
+	r.tracked = tracked
+	r.indexMod, r.indexSize = mod, size
+	r.exclude = exclude
+	r.gitignores = make(map[string]*ignoreRules)
+	r.info = nil
+	return nil
+}
+
+// status classifies path as tracked, ignored or untracked. It returns an
+// empty string for paths outside the work tree.
+func (r *gitRepo) status(path string, isDir bool) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(r.root, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isTracked(rel) {
+		return GitTracked
+	}
+	if r.isIgnored(rel, isDir) {
+		return GitIgnored
+	}
+	return GitUntracked
+}
+
+// isTracked reports whether rel is in the index or is a directory
+// containing an indexed file
+func (r *gitRepo) isTracked(rel string) bool {
+	i := r.searchIndex(rel)
+	if i < len(r.tracked) && r.tracked[i].path == rel {
+		return true
+	}
+	// "build-x" sorts between "build" and "build/", so look for the
+	// directory prefix separately
+	prefix := rel + "/"
+	i = r.searchIndex(prefix)
+	return i < len(r.tracked) && strings.HasPrefix(r.tracked[i].path, prefix)
+}
+
+// searchIndex returns the position of the first index entry not before path
+func (r *gitRepo) searchIndex(path string) int {
+	return sort.Search(len(r.tracked), func(i int) bool {
+		return r.tracked[i].path >= path
+	})
+}
+
+// isIgnored reports whether rel is ignored. As in git, nothing inside an
+// ignored directory can be re-included.
+func (r *gitRepo) isIgnored(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		if r.ignoredPath(parts[:i], i < len(parts) || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredPath applies the ignore files to a single path. Deeper .gitignore
+// files take precedence over shallower ones, which take precedence over
+// .git/info/exclude.
+func (r *gitRepo) ignoredPath(parts []string, isDir bool) bool {
+	for i := len(parts) - 1; i >= 0; i-- {
+		rules := r.gitignore(strings.Join(parts[:i], "/"))
+		if ignored, matched := rules.match(strings.Join(parts[i:], "/"), isDir); matched {
+			return ignored
+		}
+	}
+	ignored, _ := r.exclude.match(strings.Join(parts, "/"), isDir)
+	return ignored
+}
+
+// gitignore returns the rules of the .gitignore file in dir
+func (r *gitRepo) gitignore(dir string) *ignoreRules {
+	if rules, ok := r.gitignores[dir]; ok {
+		return rules
+	}
+	rules, err := readIgnoreFile(filepath.Join(r.root, filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		rules = &ignoreRules{}
+	}
+	r.gitignores[dir] = rules
+	return rules
+}
+
+// readGitIndex returns the entries of a git index file (versions 2 to 4)
+// sorted by path. A missing index, as in a fresh repository, yields no entries.
+func readGitIndex(indexPath string) ([]indexEntry, error) {
+	data, err := os.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("%s: not a git index", indexPath)
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("%s: unsupported index version %d", indexPath, version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+
+	// Fixed part of an entry: ctime, mtime, dev, ino, mode, uid, gid, size,
+	// object ID and flags
+	const entryHeader = 62
+	truncated := fmt.Errorf("%s: truncated index", indexPath)
+
+	entries := make([]indexEntry, 0, count)
+	pos := 12
+	previous := ""
+	for i := 0; i < count; i++ {
+		start := pos
+		if pos+entryHeader > len(data) {
+			return nil, truncated
+		}
+		entry := indexEntry{
+			mtime: int64(binary.BigEndian.Uint32(data[pos+8 : pos+12])),
+			mode:  binary.BigEndian.Uint32(data[pos+24 : pos+28]),
+			size:  binary.BigEndian.Uint32(data[pos+36 : pos+40]),
+		}
+		flags := binary.BigEndian.Uint16(data[pos+60 : pos+62])
+		entry.skipCheck = flags&0x8000 != 0 // assume-valid
+		pos += entryHeader
+		if version >= 3 && flags&0x4000 != 0 {
+			if pos+2 > len(data) {
+				return nil, truncated
+			}
+			extended := binary.BigEndian.Uint16(data[pos : pos+2])
+			entry.skipCheck = entry.skipCheck || extended&0x4000 != 0 // skip-worktree
+			pos += 2
+		}
+
+		var name string
+		if version == 4 {
+			// Names are prefix-compressed against the previous entry
+			strip, n := readIndexVarint(data[pos:])
+			if n == 0 || strip > len(previous) {
+				return nil, truncated
+			}
+			pos += n
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, truncated
+			}
+			name = previous[:len(previous)-strip] + string(data[pos:pos+end])
+			pos += end + 1
+		} else {
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, truncated
+			}
+			name = string(data[pos : pos+end])
+			// Entries are NUL-padded to a multiple of eight bytes
+			pos = start + (pos+end+1-start+7)/8*8
+		}
+
+		previous = name
+		// Sparse indexes record whole directories with a trailing slash
+		entry.path = strings.TrimSuffix(name, "/")
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].path < entries[j].path
+	})
+	return entries, nil
+}
+
+// readIndexVarint decodes the offset encoding used by index version 4. It
+// returns the value and the number of bytes read, or 0 bytes on error.
+func readIndexVarint(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	c := b[0]
+	value := int(c & 0x7f)
+	n := 1
+	for c&0x80 != 0 {
+		if n >= len(b) {
+			return 0, 0
+		}
+		c = b[n]
+		n++
+		value = ((value + 1) << 7) | int(c&0x7f)
+	}
+	return value, n
+}
@@ -0,0 +1,334 @@
+package cacheremover
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestIndex writes a version 2 git index for the work tree at root
+// listing paths, which must be sorted. Files that exist are recorded with
+// their current size and mtime, so they count as unmodified.
+func writeTestIndex(t *testing.T, root string, paths []string) {
+	t.Helper()
+
+	data := []byte("DIRC")
+	data = binary.BigEndian.AppendUint32(data, 2)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(paths)))
+	for _, path := range paths {
+		entry := make([]byte, 62)
+		if info, err := os.Lstat(filepath.Join(root, path)); err == nil {
+			binary.BigEndian.PutUint32(entry[8:], uint32(info.ModTime().Unix()))
+			binary.BigEndian.PutUint32(entry[36:], uint32(info.Size()))
+		}
+		binary.BigEndian.PutUint32(entry[24:], 0100644)
+		binary.BigEndian.PutUint16(entry[60:], uint16(len(path)))
+		entry = append(entry, path...)
+		for padding := 8 - len(entry)%8; padding > 0; padding-- {
+			entry = append(entry, 0)
+		}
+		data = append(data, entry...)
+	}
+
+	gitDir, ok := resolveGitDir(filepath.Join(root, ".git"))
+	if !ok {
+		t.Fatalf("No .git in %s", root)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "index"), data, 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+}
+
+// setupGitProject creates a Node.js project in a git work tree with a
+// tracked "build" folder, an ignored "dist" folder and an untracked "coverage" folder
+func setupGitProject(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	files := map[string]string{
+		"package.json":                "{}",
+		".gitignore":                  "dist/\n",
+		"build/tool.js":               "committed build script",
+		"dist/bundle.js":              "generated",
+		"coverage/lcov.info":          "generated",
+		"node_modules/x/package.json": "dependency",
+		".git/info/exclude":           "node_modules\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	writeTestIndex(t, root, []string{".gitignore", "build/tool.js", "package.json"})
+	return root
+}
+
+func TestReadGitIndex(t *testing.T) {
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	os.Mkdir(gitDir, 0755)
+	paths := []string{"a", "build/tool.js", "long/path/name/to/exercise/padding.go"}
+	writeTestIndex(t, root, paths)
+
+	got, err := readGitIndex(filepath.Join(gitDir, "index"))
+	if err != nil {
+		t.Fatalf("readGitIndex failed: %v", err)
+	}
+	if len(got) != len(paths) {
+		t.Fatalf("Expected %d paths, got %v", len(paths), got)
+	}
+	for i := range paths {
+		if got[i].path != paths[i] {
+			t.Errorf("Expected path %q, got %q", paths[i], got[i].path)
+		}
+	}
+
+	if _, err := readGitIndex(filepath.Join(gitDir, "missing")); err != nil {
+		t.Errorf("A missing index should not be an error, got %v", err)
+	}
+}
+
+func TestGitStatus(t *testing.T) {
+	root := setupGitProject(t)
+	repo := findGitRepo(filepath.Join(root, "build"))
+	if repo == nil {
+		t.Fatal("Expected to find the git work tree")
+	}
+	if err := repo.refresh(); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	tests := map[string]string{
+		"build":        GitTracked,
+		"dist":         GitIgnored,
+		"node_modules": GitIgnored,
+		"coverage":     GitUntracked,
+	}
+	for name, expected := range tests {
+		if status := repo.status(filepath.Join(root, name), true); status != expected {
+			t.Errorf("Expected %s to be %s, got %q", name, expected, status)
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules([]string{
+		"# comment",
+		"*.log",
+		"!important.log",
+		"/out/",
+		"docs/**/generated",
+		"cache/",
+	})
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"debug.log", false, true},
+		{"nested/debug.log", false, true},
+		{"important.log", false, false},
+		{"out", true, true},
+		{"src/out", true, false},
+		{"docs/generated", true, true},
+		{"docs/a/b/generated", true, true},
+		{"cache", true, true},
+		{"cache", false, false},
+	}
+	for _, tt := range tests {
+		if ignored, _ := rules.match(tt.path, tt.isDir); ignored != tt.ignored {
+			t.Errorf("match(%q, %v) = %v, expected %v", tt.path, tt.isDir, ignored, tt.ignored)
+		}
+	}
+}
+
+func TestScannerSkipsTrackedItems(t *testing.T) {
+	root := setupGitProject(t)
+	scanner := newTestScanner()
+
+	var skipped []string
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventTrackedSkipped {
+			skipped = append(skipped, event.Path)
+		}
+	}
+
+	project, err := scanner.ScanProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ScanProject failed: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, item := range project.Items {
+		statuses[filepath.Base(item.Path)] = item.GitStatus
+	}
+	if _, ok := statuses["build"]; ok {
+		t.Error("Tracked build directory should not be a cache item")
+	}
+	if statuses["dist"] != GitIgnored || statuses["coverage"] != GitUntracked {
+		t.Errorf("Unexpected git statuses: %v", statuses)
+	}
+	if len(skipped) != 1 || skipped[0] != filepath.Join(root, "build") {
+		t.Errorf("Expected one skip event for build, got %v", skipped)
+	}
+
+	scanner.IncludeTracked = true
+	project, err = scanner.ScanProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ScanProject failed: %v", err)
+	}
+	for _, item := range project.Items {
+		if filepath.Base(item.Path) == "build" && item.GitStatus == GitTracked {
+			return
+		}
+	}
+	t.Error("IncludeTracked should keep the tracked build directory")
+}
+
+func TestScannerKeepsItemsWithUnreadableIndex(t *testing.T) {
+	root := setupGitProject(t)
+	indexPath := filepath.Join(root, ".git", "index")
+	data, _ := os.ReadFile(indexPath)
+	os.WriteFile(indexPath, data[:len(data)-20], 0644)
+
+	scanner := newTestScanner()
+	var kept []string
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventGitIndexUnreadable && event.Err != nil {
+			kept = append(kept, filepath.Base(event.Path))
+		}
+	}
+
+	project, err := scanner.ScanProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ScanProject failed: %v", err)
+	}
+	if len(project.Items) != 0 {
+		t.Errorf("Expected no items without a readable index, got %+v", project.Items)
+	}
+	sort.Strings(kept)
+	if got := strings.Join(kept, ","); got != "build,coverage,dist,node_modules" {
+		t.Errorf("Expected every cache item reported, got %s", got)
+	}
+
+	scanner.IncludeTracked = true
+	project, err = scanner.ScanProject(context.Background(), root)
+	if err != nil || len(project.Items) != 4 {
+		t.Errorf("IncludeTracked should keep all items, got %+v (%v)", project, err)
+	}
+}
+
+// writeTestCommit stores a loose commit object committed at when and returns its ID
+func writeTestCommit(t *testing.T, gitDir string, when time.Time) string {
+	t.Helper()
+
+	body := fmt.Sprintf("tree %s\nauthor A <a@example.com> %d +0000\ncommitter C <c@example.com> %d +0000\n\nmessage\n",
+		strings.Repeat("0", 40), when.Unix(), when.Unix())
+	object := fmt.Sprintf("commit %d\x00%s", len(body), body)
+	id := fmt.Sprintf("%x", sha1.Sum([]byte(object)))
+
+	var compressed bytes.Buffer
+	z := zlib.NewWriter(&compressed)
+	z.Write([]byte(object))
+	z.Close()
+
+	dir := filepath.Join(gitDir, "objects", id[:2])
+	os.MkdirAll(dir, 0755)
+	if err := os.WriteFile(filepath.Join(dir, id[2:]), compressed.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write commit object: %v", err)
+	}
+	return id
+}
+
+func TestRepoInfo(t *testing.T) {
+	root := setupGitProject(t)
+	gitDir := filepath.Join(root, ".git")
+	committed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	id := writeTestCommit(t, gitDir, committed)
+
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0644)
+	os.WriteFile(filepath.Join(gitDir, "packed-refs"), []byte("# pack-refs with: peeled\n"+id+" refs/heads/main\n"), 0644)
+
+	scanner := newTestScanner()
+	info := scanner.RepoInfo(filepath.Join(root, "build"))
+	if info == nil {
+		t.Fatal("Expected repository information")
+	}
+	if info.Branch != "main" || info.Detached || info.Commit != id {
+		t.Errorf("Unexpected HEAD: %+v", info)
+	}
+	if !info.LastCommit.Equal(committed) {
+		t.Errorf("Expected last commit at %v, got %v", committed, info.LastCommit)
+	}
+	if info.Dirty {
+		t.Error("Unmodified work tree should be clean")
+	}
+
+	// Detached HEAD in a linked worktree with a modified file
+	worktree := t.TempDir()
+	worktreeGitDir := filepath.Join(gitDir, "worktrees", "wt")
+	os.MkdirAll(worktreeGitDir, 0755)
+	os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+worktreeGitDir+"\n"), 0644)
+	os.WriteFile(filepath.Join(worktreeGitDir, "commondir"), []byte("../..\n"), 0644)
+	os.WriteFile(filepath.Join(worktreeGitDir, "HEAD"), []byte(id+"\n"), 0644)
+	os.WriteFile(filepath.Join(worktree, "package.json"), []byte("{}"), 0644)
+	writeTestIndex(t, worktree, []string{"package.json"})
+	os.WriteFile(filepath.Join(worktree, "package.json"), []byte(`{"name": "changed"}`), 0644)
+
+	info = scanner.RepoInfo(worktree)
+	if info == nil || !info.Detached || info.Commit != id || !info.Dirty {
+		t.Errorf("Expected a dirty detached worktree, got %+v", info)
+	}
+	if info != nil && !info.LastCommit.Equal(committed) {
+		t.Errorf("Worktree should read commits from the common directory, got %v", info.LastCommit)
+	}
+}
+
+func TestRepoInfoFromOnEvent(t *testing.T) {
+	root := setupGitProject(t)
+	os.WriteFile(filepath.Join(root, ".git", "index"), []byte("not an index"), 0644)
+
+	// OnEvent calls are serialized, so an event reported by RepoInfo would
+	// wait for the callback calling it
+	scanner := newTestScanner()
+	var errors int
+	scanner.OnEvent = func(event Event) {
+		switch event.Kind {
+		case EventGitIndexUnreadable:
+			errors++
+		case EventProjectScanned:
+			if info := scanner.RepoInfo(event.Path); info != nil {
+				t.Errorf("Expected no information with an unreadable index, got %+v", info)
+			}
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := scanner.Scan(context.Background(), root)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Scan did not finish")
+	}
+	if errors == 0 {
+		t.Error("Expected the scan itself to report the unreadable index")
+	}
+}
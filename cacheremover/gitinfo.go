@@ -0,0 +1,311 @@
+package cacheremover
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RepoInfo summarises the git checkout a project lives in
+type RepoInfo struct {
+	// Root is the work tree root
+	Root string
+	// Branch is the checked-out branch, or empty when HEAD is detached
+	Branch string
+	// Commit is the object ID HEAD resolves to; empty on a branch with no commits yet
+	Commit string
+	// Detached is true when HEAD points at a commit rather than a branch
+	Detached bool
+	// Dirty is true when a tracked file differs from the index, judged by
+	// size and modification time as `git status` does before hashing
+	Dirty bool
+	// LastCommit is when HEAD was committed, or the zero time if unknown
+	LastCommit time.Time
+}
+
+// RepoInfo returns the state of the git checkout containing projectPath, or
+// nil if it is not inside a git work tree. Results are cached until the next
+// FindProjects call, so projects sharing a repository are cheap to query.
+// A repository whose index cannot be read gives nil too. RepoInfo reports
+// no events, so it may be called from OnEvent or while a scan waits on it.
+func (s *Scanner) RepoInfo(projectPath string) *RepoInfo {
+	repo, err := s.loadGitRepo(projectPath)
+	if repo == nil || err != nil {
+		return nil
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.info == nil {
+		repo.info = repo.readInfo()
+	}
+	info := *repo.info
+	return &info
+}
+
+func (r *gitRepo) readInfo() *RepoInfo {
+	info := &RepoInfo{Root: r.root}
+
+	head, err := os.ReadFile(filepath.Join(r.gitDir, "HEAD"))
+	if err != nil {
+		return info
+	}
+	ref := strings.TrimSpace(string(head))
+	if strings.HasPrefix(ref, "ref:") {
+		ref = strings.TrimSpace(strings.TrimPrefix(ref, "ref:"))
+		info.Branch = strings.TrimPrefix(ref, "refs/heads/")
+		info.Commit = r.resolveRef(ref)
+	} else {
+		info.Detached = true
+		info.Commit = ref
+	}
+
+	if info.Commit != "" {
+		info.LastCommit = r.commitTime(info.Commit)
+	}
+	if info.LastCommit.IsZero() {
+		info.LastCommit = r.reflogTime(ref)
+	}
+	info.Dirty = r.isDirty()
+	return info
+}
+
+// resolveRef follows ref to an object ID using loose refs, then packed-refs
+func (r *gitRepo) resolveRef(ref string) string {
+	for depth := 0; depth < 5; depth++ {
+		var value string
+		// Per-worktree refs live in gitDir, branches in the shared commonDir
+		for _, dir := range []string{r.gitDir, r.commonDir} {
+			if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+				value = strings.TrimSpace(string(data))
+				break
+			}
+		}
+		if value == "" {
+			return r.packedRef(ref)
+		}
+		if !strings.HasPrefix(value, "ref:") {
+			return value
+		}
+		ref = strings.TrimSpace(strings.TrimPrefix(value, "ref:"))
+	}
+	return ""
+}
+
+func (r *gitRepo) packedRef(ref string) string {
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		id, name, ok := strings.Cut(lines.Text(), " ")
+		if ok && name == ref {
+			return id
+		}
+	}
+	return ""
+}
+
+// commitTime reads the committer date of a commit from the object store.
+// Loose objects and undeltified packed objects are supported; anything else
+// yields the zero time.
+func (r *gitRepo) commitTime(id string) time.Time {
+	objects := filepath.Join(r.commonDir, "objects")
+	if len(id) < 3 {
+		return time.Time{}
+	}
+
+	if f, err := os.Open(filepath.Join(objects, id[:2], id[2:])); err == nil {
+		defer f.Close()
+		if z, err := zlib.NewReader(f); err == nil {
+			defer z.Close()
+			return parseCommitTime(z)
+		}
+		return time.Time{}
+	}
+
+	raw, err := hex.DecodeString(id)
+	if err != nil {
+		return time.Time{}
+	}
+	indexes, _ := filepath.Glob(filepath.Join(objects, "pack", "*.idx"))
+	for _, idx := range indexes {
+		offset, ok := findPackOffset(idx, raw)
+		if !ok {
+			continue
+		}
+		return readPackedCommitTime(strings.TrimSuffix(idx, ".idx")+".pack", offset)
+	}
+	return time.Time{}
+}
+
+// findPackOffset looks up an object ID in a version 2 pack index
+func findPackOffset(idxPath string, id []byte) (int64, bool) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil || len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) {
+		return 0, false
+	}
+	if binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return 0, false
+	}
+
+	fanout := data[8 : 8+256*4]
+	count := int(binary.BigEndian.Uint32(fanout[255*4:]))
+	hashSize := len(id)
+	idsStart := 8 + 256*4
+	crcStart := idsStart + count*hashSize
+	offsetStart := crcStart + count*4
+	largeStart := offsetStart + count*4
+	if len(data) < largeStart {
+		return 0, false
+	}
+
+	lo := 0
+	if id[0] > 0 {
+		lo = int(binary.BigEndian.Uint32(fanout[(int(id[0])-1)*4:]))
+	}
+	hi := int(binary.BigEndian.Uint32(fanout[int(id[0])*4:]))
+	for lo < hi {
+		mid := (lo + hi) / 2
+		cmp := bytes.Compare(data[idsStart+mid*hashSize:idsStart+(mid+1)*hashSize], id)
+		switch {
+		case cmp == 0:
+			offset := int64(binary.BigEndian.Uint32(data[offsetStart+mid*4:]))
+			if offset&0x80000000 != 0 {
+				large := largeStart + int(offset&0x7fffffff)*8
+				if len(data) < large+8 {
+					return 0, false
+				}
+				offset = int64(binary.BigEndian.Uint64(data[large:]))
+			}
+			return offset, true
+		case cmp < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, false
+}
+
+func readPackedCommitTime(packPath string, offset int64) time.Time {
+	f, err := os.Open(packPath)
+	if err != nil {
+		return time.Time{}
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return time.Time{}
+	}
+	reader := bufio.NewReader(f)
+
+	// Object header: 3-bit type and a variable-length size
+	c, err := reader.ReadByte()
+	if err != nil {
+		return time.Time{}
+	}
+	const packedCommit = 1
+	if (c>>4)&7 != packedCommit {
+		return time.Time{} // Deltified or not a commit
+	}
+	for c&0x80 != 0 {
+		if c, err = reader.ReadByte(); err != nil {
+			return time.Time{}
+		}
+	}
+
+	z, err := zlib.NewReader(reader)
+	if err != nil {
+		return time.Time{}
+	}
+	defer z.Close()
+	return parseCommitTime(io.MultiReader(strings.NewReader("commit 0\x00"), z))
+}
+
+// parseCommitTime extracts the committer date from a commit object,
+// including its "commit <size>\0" header
+func parseCommitTime(r io.Reader) time.Time {
+	lines := bufio.NewReader(io.LimitReader(r, 64*1024))
+	if _, err := lines.ReadString(0); err != nil {
+		return time.Time{}
+	}
+	for {
+		line, err := lines.ReadString('\n')
+		if strings.HasPrefix(line, "committer ") {
+			return parseSignatureTime(strings.TrimSpace(line))
+		}
+		if err != nil || line == "\n" {
+			return time.Time{} // End of headers
+		}
+	}
+}
+
+// parseSignatureTime parses the "<unix seconds> <zone>" suffix of a commit
+// signature or reflog entry
+func parseSignatureTime(signature string) time.Time {
+	fields := strings.Fields(signature[strings.LastIndex(signature, ">")+1:])
+	if len(fields) == 0 {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// reflogTime returns the time of the last reflog entry for ref, a fallback
+// when the commit object cannot be read
+func (r *gitRepo) reflogTime(ref string) time.Time {
+	for _, path := range []string{
+		filepath.Join(r.commonDir, "logs", filepath.FromSlash(ref)),
+		filepath.Join(r.gitDir, "logs", "HEAD"),
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		last, _, _ := strings.Cut(lines[len(lines)-1], "\t")
+		if t := parseSignatureTime(last); !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// isDirty reports whether any tracked file was modified or deleted since it
+// was staged. Like git, it trusts matching size and mtime without hashing.
+func (r *gitRepo) isDirty() bool {
+	const (
+		modeMask    = 0170000
+		modeGitLink = 0160000 // Submodule
+		modeDir     = 0040000 // Sparse index directory
+	)
+
+	for _, entry := range r.tracked {
+		if entry.skipCheck || entry.mode&modeMask == modeGitLink || entry.mode&modeMask == modeDir {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(r.root, filepath.FromSlash(entry.path)))
+		if err != nil {
+			return true
+		}
+		if uint32(info.Size()) != entry.size || info.ModTime().Unix() != entry.mtime {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package cacheremover
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Strategies for pruning a global cache
+const (
+	// GlobalStrategyUnused removes entries nobody has used for UnusedDays
+	GlobalStrategyUnused = "unused"
+	// GlobalStrategyAll removes the whole contents of the cache. With
+	// UnusedDays set, only once nothing in it has been used for that long.
+	GlobalStrategyAll = "all"
+)
+
+// GlobalCache is a toolchain cache shared by all projects of a user, such
+// as the Maven repository or the Go build cache
+type GlobalCache struct {
+	Name string `json:"name"`
+	// Path may start with "~" and use $VARIABLES; "{user_cache}" stands for
+	// the platform's user cache directory (~/.cache, ~/Library/Caches, ...)
+	Path       string `json:"path"`
+	Strategy   string `json:"strategy"`
+	UnusedDays int    `json:"unused_days,omitempty"`
+	// EntryDepth sets what the unused strategy removes as a unit: 0 means
+	// single files, N means whole paths N levels below Path, such as
+	// group/artifact/version in a Gradle module cache
+	EntryDepth int `json:"entry_depth,omitempty"`
+}
+
+// ResolvePath expands the cache path. It reports false when the path uses
+// an environment variable that is not set, or the home or cache directory
+// is unknown, so that a cache is never resolved to the wrong place.
+func (g GlobalCache) ResolvePath() (string, bool) {
+	ok := true
+	expanded := os.Expand(g.Path, func(name string) string {
+		value := os.Getenv(name)
+		if value == "" {
+			ok = false
+		}
+		return value
+	})
+
+	if rest, found := strings.CutPrefix(expanded, "{user_cache}"); found {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", false
+		}
+		expanded = dir + rest
+	}
+	if expanded == "~" || strings.HasPrefix(expanded, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		expanded = home + expanded[1:]
+	}
+
+	if !ok || !filepath.IsAbs(expanded) {
+		return "", false
+	}
+	return filepath.Clean(expanded), true
+}
+
+// validate checks the settings of a global cache
+func (g GlobalCache) validate() error {
+	if g.Name == "" || g.Path == "" {
+		return fmt.Errorf("global cache needs a name and a path")
+	}
+	switch g.Strategy {
+	case GlobalStrategyAll:
+	case GlobalStrategyUnused:
+		if g.UnusedDays <= 0 {
+			return fmt.Errorf("global cache '%s': strategy %q needs unused_days", g.Name, g.Strategy)
+		}
+	default:
+		return fmt.Errorf("global cache '%s': unknown strategy %q (use %q or %q)",
+			g.Name, g.Strategy, GlobalStrategyUnused, GlobalStrategyAll)
+	}
+	if g.EntryDepth < 0 || g.UnusedDays < 0 {
+		return fmt.Errorf("global cache '%s': entry_depth and unused_days cannot be negative", g.Name)
+	}
+	return nil
+}
+
+// GlobalCacheUsage is the state of a global cache on this machine
+type GlobalCacheUsage struct {
+	Cache GlobalCache
+	// Path is the resolved location; empty when it cannot be resolved
+	Path string
+	// Exists is false when the toolchain is not installed or never used
+	Exists bool
+	// Size is the disk space used by the cache
+	Size int64
+	// Entries is the number of entries in the cache
+	Entries int
+	// LastUsed is the most recent access to anything in the cache
+	LastUsed time.Time
+	// NoAccessTimes is true when no file in the cache was read after it was
+	// written, as on filesystems mounted with noatime. The last use of an
+	// entry cannot be told from its last write then, so strategies based on
+	// UnusedDays select nothing rather than entries still read every day.
+	NoAccessTimes bool
+	// Stale lists the entries the cache's strategy would remove
+	Stale     []CacheItem
+	StaleSize int64
+	// StaleApparentSize is the apparent size of the stale entries
+	StaleApparentSize int64
+}
+
+// ScanGlobalCache sizes a global cache and selects the entries its strategy
+// would prune as of now
+func ScanGlobalCache(ctx context.Context, cache GlobalCache, now time.Time) (*GlobalCacheUsage, error) {
+	usage := &GlobalCacheUsage{Cache: cache}
+	root, ok := cache.ResolvePath()
+	if !ok {
+		return usage, nil
+	}
+	usage.Path = root
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return usage, nil
+	}
+	usage.Exists = true
+
+	// The all strategy removes the direct children, leaving the cache
+	// directory itself in place
+	depth := cache.EntryDepth
+	if cache.Strategy == GlobalStrategyAll {
+		depth = 1
+	}
+	entries, err := globalEntries(ctx, root, depth)
+	if err != nil {
+		return usage, err
+	}
+
+	read := false
+	for _, entry := range entries {
+		usage.Size += entry.item.Size
+		usage.Entries++
+		if entry.lastUsed.After(usage.LastUsed) {
+			usage.LastUsed = entry.lastUsed
+		}
+		read = read || entry.read
+	}
+	if cache.UnusedDays > 0 && len(entries) > 0 && !read {
+		usage.NoAccessTimes = true
+		return usage, nil
+	}
+
+	cutoff := now.AddDate(0, 0, -cache.UnusedDays)
+	for _, entry := range entries {
+		if cache.Strategy == GlobalStrategyUnused && entry.lastUsed.Before(cutoff) {
+			usage.Stale = append(usage.Stale, entry.item)
+			usage.StaleSize += entry.item.Size
+			usage.StaleApparentSize += entry.item.ApparentSize
+		}
+	}
+
+	if cache.Strategy == GlobalStrategyAll && (cache.UnusedDays == 0 || usage.LastUsed.Before(cutoff)) {
+		for _, entry := range entries {
+			usage.Stale = append(usage.Stale, entry.item)
+			usage.StaleApparentSize += entry.item.ApparentSize
+		}
+		usage.StaleSize = usage.Size
+	}
+	return usage, nil
+}
+
+// cacheEntry is a prunable unit of a global cache
+type cacheEntry struct {
+	item     CacheItem
+	lastUsed time.Time
+	read     bool // A file of the entry was read after it was written
+}
+
+// globalEntries lists the entries of a global cache: every file when depth
+// is 0, otherwise every path depth levels below root. Files closer to the
+// root than depth are entries of their own.
+func globalEntries(ctx context.Context, root string, depth int) ([]cacheEntry, error) {
+	var entries []cacheEntry
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || path == root {
+			return nil
+		}
+
+		level := strings.Count(relSlash(root, path), "/") + 1
+		if info.IsDir() && (depth == 0 || level < depth) {
+			return nil
+		}
+
+		entry := cacheEntry{item: CacheItem{Path: path, Type: "file"}}
+		if info.IsDir() {
+			entry.item.Type = "directory"
+			entry.item.Size, entry.item.ApparentSize, entry.lastUsed, entry.read = treeUsage(path)
+		} else {
+			entry.item.Size, entry.item.ApparentSize = fileSizes(info)
+			entry.lastUsed, entry.read = lastUsed(info), readSinceWritten(info)
+		}
+		entries = append(entries, entry)
+
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// treeUsage returns the disk and apparent size of dir, when any file below
+// it was last used and whether one was read after it was written. The
+// access time of directories is ignored: listing them, as this scan does,
+// updates it.
+func treeUsage(dir string) (size, apparent int64, last time.Time, read bool) {
+	var counter sizeCounter
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		counter.add(info)
+		if info.IsDir() {
+			return nil
+		}
+		if used := lastUsed(info); used.After(last) {
+			last = used
+		}
+		read = read || readSinceWritten(info)
+		return nil
+	})
+	size, apparent = counter.sizes()
+	return size, apparent, last, read
+}
+
+// CleanGlobalCache removes the stale entries of a scanned global cache and
+// then any directories left empty below the cache root
+func (c *Cleaner) CleanGlobalCache(ctx context.Context, usage *GlobalCacheUsage) (*CleanResult, error) {
+	result, err := c.clean(ctx, usage.Stale, usage.Cache.Name)
+	for _, item := range result.Removed {
+		removeEmptyParents(usage.Path, filepath.Dir(item.Path))
+	}
+	return result, err
+}
+
+// removeEmptyParents removes dir and its parents up to, but not including,
+// root for as long as they are empty
+func removeEmptyParents(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
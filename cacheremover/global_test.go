@@ -0,0 +1,153 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGlobalCacheResolvePath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	t.Setenv("CACHE_REMOVER_TEST_DIR", "/opt/cache")
+	os.Unsetenv("CACHE_REMOVER_UNSET")
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"~/.m2/repository", filepath.Join(home, ".m2", "repository"), true},
+		{"$CACHE_REMOVER_TEST_DIR/pip", filepath.FromSlash("/opt/cache/pip"), true},
+		// An unset variable must not resolve to a directory near the root
+		{"$CACHE_REMOVER_UNSET/pkg/mod", "", false},
+		{"relative/cache", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := GlobalCache{Path: tt.path}.ResolvePath()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ResolvePath(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// writeAged writes a file last used age ago, read an hour after it was
+// written, the way a filesystem recording access times shows it
+func writeAged(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte("cached data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Now().Add(-age)
+	if err := os.Chtimes(path, when, when.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanGlobalCache(t *testing.T) {
+	root := t.TempDir()
+	day := 24 * time.Hour
+	writeAged(t, filepath.Join(root, "org/old/1.0/old.jar"), 200*day)
+	writeAged(t, filepath.Join(root, "org/old/1.0/old.pom"), 200*day)
+	writeAged(t, filepath.Join(root, "org/mixed/2.0/mixed.jar"), 200*day)
+	writeAged(t, filepath.Join(root, "org/mixed/2.0/mixed.pom"), day)
+	writeAged(t, filepath.Join(root, "com/new/3.0/new.jar"), day)
+
+	ctx := context.Background()
+	files := GlobalCache{Name: "files", Path: root, Strategy: GlobalStrategyUnused, UnusedDays: 90}
+	usage, err := ScanGlobalCache(ctx, files, time.Now())
+	if err != nil {
+		t.Fatalf("ScanGlobalCache failed: %v", err)
+	}
+	if !usage.Exists || usage.Entries != 5 || len(usage.Stale) != 3 {
+		t.Errorf("Expected 3 of 5 files to be stale, got %d of %d", len(usage.Stale), usage.Entries)
+	}
+
+	// Versions are only removed when nothing inside them was used
+	versions := files
+	versions.EntryDepth = 3
+	usage, err = ScanGlobalCache(ctx, versions, time.Now())
+	if err != nil {
+		t.Fatalf("ScanGlobalCache failed: %v", err)
+	}
+	if usage.Entries != 3 || len(usage.Stale) != 1 || usage.Stale[0].Path != filepath.Join(root, "org/old/1.0") {
+		t.Errorf("Expected only org/old/1.0 to be stale, got %v", usage.Stale)
+	}
+
+	all := GlobalCache{Name: "all", Path: root, Strategy: GlobalStrategyAll, UnusedDays: 90}
+	if usage, _ := ScanGlobalCache(ctx, all, time.Now()); len(usage.Stale) != 0 {
+		t.Errorf("A recently used cache should be kept, got %v", usage.Stale)
+	}
+	if usage, _ := ScanGlobalCache(ctx, all, time.Now().Add(365*day)); len(usage.Stale) != 2 || usage.StaleSize != usage.Size {
+		t.Errorf("An unused cache should be removed entirely, got %v", usage.Stale)
+	}
+
+	missing := GlobalCache{Name: "missing", Path: filepath.Join(root, "nope"), Strategy: GlobalStrategyAll}
+	if usage, err := ScanGlobalCache(ctx, missing, time.Now()); err != nil || usage.Exists {
+		t.Errorf("A missing cache should be reported as not existing, got %v, %v", usage, err)
+	}
+}
+
+func TestCleanGlobalCache(t *testing.T) {
+	root := t.TempDir()
+	day := 24 * time.Hour
+	writeAged(t, filepath.Join(root, "a/b/old.bin"), 100*day)
+	writeAged(t, filepath.Join(root, "a/new.bin"), 0)
+
+	cache := GlobalCache{Name: "test", Path: root, Strategy: GlobalStrategyUnused, UnusedDays: 30}
+	usage, err := ScanGlobalCache(context.Background(), cache, time.Now())
+	if err != nil {
+		t.Fatalf("ScanGlobalCache failed: %v", err)
+	}
+
+	config := DefaultConfig()
+	result, err := NewCleaner(&config).CleanGlobalCache(context.Background(), usage)
+	if err != nil || len(result.Removed) != 1 {
+		t.Fatalf("Expected 1 removed entry, got %v, %v", result, err)
+	}
+
+	// The emptied directory is pruned, the one still in use is kept
+	if _, err := os.Stat(filepath.Join(root, "a/b")); !os.IsNotExist(err) {
+		t.Error("Empty directory a/b should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(root, "a/new.bin")); err != nil {
+		t.Errorf("Recently used file should be kept: %v", err)
+	}
+}
+
+func TestScanGlobalCacheWithoutAccessTimes(t *testing.T) {
+	root := t.TempDir()
+	day := 24 * time.Hour
+	for _, name := range []string{"org/lib/1.0/lib.jar", "org/lib/2.0/lib.jar"} {
+		writeAged(t, filepath.Join(root, name), 200*day)
+		// On a noatime mount the access time stays at the write
+		path := filepath.Join(root, name)
+		info, _ := os.Stat(path)
+		os.Chtimes(path, info.ModTime(), info.ModTime())
+	}
+
+	for _, cache := range []GlobalCache{
+		{Name: "files", Path: root, Strategy: GlobalStrategyUnused, UnusedDays: 90},
+		{Name: "versions", Path: root, Strategy: GlobalStrategyUnused, UnusedDays: 90, EntryDepth: 3},
+		{Name: "all", Path: root, Strategy: GlobalStrategyAll, UnusedDays: 90},
+	} {
+		usage, err := ScanGlobalCache(context.Background(), cache, time.Now())
+		if err != nil {
+			t.Fatalf("ScanGlobalCache failed: %v", err)
+		}
+		if !usage.NoAccessTimes || len(usage.Stale) != 0 || usage.Entries == 0 {
+			t.Errorf("%s: expected nothing selected without access times, got %+v", cache.Name, usage)
+		}
+	}
+
+	// Removing everything does not depend on access times
+	all := GlobalCache{Name: "all", Path: root, Strategy: GlobalStrategyAll}
+	if usage, _ := ScanGlobalCache(context.Background(), all, time.Now()); usage.NoAccessTimes || len(usage.Stale) == 0 || usage.StaleSize != usage.Size {
+		t.Errorf("Expected the whole cache selected, got %+v", usage)
+	}
+}
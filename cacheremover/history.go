@@ -0,0 +1,177 @@
+package cacheremover
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HistoryRecord describes one completed cleanup run
+type HistoryRecord struct {
+	ID             string           `json:"id"`
+	Time           time.Time        `json:"time"`
+	RootDir        string           `json:"root_dir"`
+	Source         string           `json:"source"`
+	Trashed        bool             `json:"trashed"`
+	Projects       []HistoryProject `json:"projects"`
+	ItemsRemoved   int              `json:"items_removed"`
+	BytesReclaimed int64            `json:"bytes_reclaimed"`
+	Failures       int              `json:"failures"`
+	Duration       time.Duration    `json:"duration_ns"`
+}
+
+// HistoryProject is the per-project part of a HistoryRecord
+type HistoryProject struct {
+	Path         string           `json:"path"`
+	Type         string           `json:"type"`
+	Removed      []CacheItem      `json:"removed"`
+	Failed       []HistoryFailure `json:"failed,omitempty"`
+	BytesRemoved int64            `json:"bytes_removed"`
+}
+
+// HistoryFailure records a cache item that could not be removed
+type HistoryFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// NewHistoryRecord starts a record for a run beginning now
+func NewHistoryRecord(rootDir, source string) *HistoryRecord {
+	now := time.Now()
+	return &HistoryRecord{
+		ID:      now.Format("20060102-150405.000"),
+		Time:    now,
+		RootDir: absPath(rootDir),
+		Source:  source,
+	}
+}
+
+// AddProject adds the outcome of cleaning one project to the record. Paths
+// are stored as absolute paths so the history stays meaningful later.
+func (r *HistoryRecord) AddProject(path, projectType string, result *CleanResult) {
+	project := HistoryProject{
+		Path:         absPath(path),
+		Type:         projectType,
+		Removed:      make([]CacheItem, 0, len(result.Removed)),
+		BytesRemoved: result.BytesRemoved,
+	}
+	for _, item := range result.Removed {
+		item.Path = absPath(item.Path)
+		project.Removed = append(project.Removed, item)
+	}
+	for _, failure := range result.Failed {
+		project.Failed = append(project.Failed, HistoryFailure{
+			Path:  absPath(failure.Item.Path),
+			Error: failure.Err.Error(),
+		})
+	}
+
+	r.Projects = append(r.Projects, project)
+	r.ItemsRemoved += len(result.Removed)
+	r.BytesReclaimed += result.BytesRemoved
+	r.Failures += len(result.Failed)
+	r.Trashed = r.Trashed || result.Trashed
+}
+
+// absPath returns path made absolute, or path unchanged if that fails
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// History is an append-only log of cleanup runs stored as JSON lines
+type History struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewHistory creates a History stored at path
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+// DefaultHistoryPath returns the history file under ~/.cache-remover
+func DefaultHistoryPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache-remover", "history.jsonl")
+}
+
+// Path returns the history file location
+func (h *History) Path() string {
+	return h.path
+}
+
+// Append adds a record to the end of the history file
+func (h *History) Append(record *HistoryRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Records returns every run in the history, oldest first. Lines that cannot
+// be parsed, such as a partially written last line, are skipped.
+func (h *History) Records() ([]HistoryRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	lines := bufio.NewScanner(f)
+	lines.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lines.Scan() {
+		var record HistoryRecord
+		if err := json.Unmarshal(lines.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := lines.Err(); err != nil {
+		return records, fmt.Errorf("reading %s: %v", h.path, err)
+	}
+	return records, nil
+}
+
+// Find returns the record with the given ID
+func (h *History) Find(id string) (*HistoryRecord, error) {
+	records, err := h.Records()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].ID == id {
+			return &records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no run with ID %q in %s", id, h.path)
+}
@@ -0,0 +1,64 @@
+package cacheremover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryAppendAndRecords(t *testing.T) {
+	history := NewHistory(filepath.Join(t.TempDir(), "nested", "history.jsonl"))
+
+	records, err := history.Records()
+	if err != nil || len(records) != 0 {
+		t.Fatalf("Expected empty history, got %d records (%v)", len(records), err)
+	}
+
+	record := NewHistoryRecord("/projects", "cli")
+	record.AddProject("/projects/app", "Node.js", &CleanResult{
+		Removed:      []CacheItem{{Path: "/projects/app/node_modules", Size: 1024, Type: "directory"}},
+		Failed:       []RemoveFailure{{Item: CacheItem{Path: "/projects/app/dist"}, Err: errors.New("permission denied")}},
+		BytesRemoved: 1024,
+	})
+	if err := history.Append(record); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	records, err = history.Records()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d (%v)", len(records), err)
+	}
+
+	got := records[0]
+	if got.BytesReclaimed != 1024 || got.ItemsRemoved != 1 || got.Failures != 1 {
+		t.Errorf("Unexpected totals: %+v", got)
+	}
+	if len(got.Projects) != 1 || got.Projects[0].Failed[0].Error != "permission denied" {
+		t.Errorf("Unexpected projects: %+v", got.Projects)
+	}
+
+	found, err := history.Find(record.ID)
+	if err != nil || found.RootDir != "/projects" {
+		t.Errorf("Find(%s) = %+v, %v", record.ID, found, err)
+	}
+	if _, err := history.Find("missing"); err == nil {
+		t.Error("Find should fail for unknown IDs")
+	}
+}
+
+func TestHistorySkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	history := NewHistory(path)
+
+	history.Append(NewHistoryRecord("/a", "cli"))
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString("{\"id\": \"truncated\n")
+	f.Close()
+	history.Append(NewHistoryRecord("/b", "tui"))
+
+	records, err := history.Records()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Expected 2 valid records, got %d (%v)", len(records), err)
+	}
+}
@@ -0,0 +1,162 @@
+package cacheremover
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignoreRule is a single line of a gitignore-style file
+type ignoreRule struct {
+	segments []string // Pattern split on "/"
+	negate   bool     // Pattern started with "!"
+	dirOnly  bool     // Pattern ended with "/"
+	anchored bool     // Pattern contained a "/" other than a trailing one
+}
+
+// ignoreRules is an ordered list of gitignore rules read from one file. The
+// patterns are relative to the directory containing the file.
+type ignoreRules struct {
+	rules []ignoreRule
+}
+
+// readIgnoreFile parses a gitignore-style file. A missing file yields an
+// empty rule set.
+func readIgnoreFile(filePath string) (*ignoreRules, error) {
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return &ignoreRules{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return parseIgnoreRules(lines), scanner.Err()
+}
+
+// parseIgnoreRules parses lines in gitignore syntax
+func parseIgnoreRules(lines []string) *ignoreRules {
+	rules := &ignoreRules{}
+	for _, line := range lines {
+		if rule, ok := parseIgnoreLine(line); ok {
+			rules.rules = append(rules.rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+
+	line = strings.TrimSuffix(line, "\r")
+	line = trimUnescapedSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	// gitignore uses [!...] for negated classes; path.Match expects [^...]
+	line = strings.ReplaceAll(line, "[!", "[^")
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// trimUnescapedSpaces removes trailing spaces unless they are escaped with a backslash
+func trimUnescapedSpaces(line string) string {
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' {
+		if end >= 2 && line[end-2] == '\\' {
+			return line[:end-2] + " "
+		}
+		end--
+	}
+	return line[:end]
+}
+
+// match reports whether relPath, a slash-separated path relative to the
+// rules' directory, is ignored or explicitly re-included. matched is false
+// when no rule applies.
+func (r *ignoreRules) match(relPath string, isDir bool) (ignored, matched bool) {
+	if r == nil {
+		return false, false
+	}
+
+	parts := strings.Split(relPath, "/")
+	// The last matching rule wins
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		rule := r.rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(parts) {
+			return !rule.negate, true
+		}
+	}
+	return false, false
+}
+
+func (rule ignoreRule) matches(parts []string) bool {
+	if !rule.anchored {
+		// A pattern without a slash matches the name at any depth
+		ok, _ := path.Match(rule.segments[0], parts[len(parts)-1])
+		return ok
+	}
+	return matchSegments(rule.segments, parts)
+}
+
+// matchSegments matches path segments against pattern segments, where a
+// "**" segment matches zero or more path segments
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				// A trailing "/**" matches everything inside, but not the directory itself
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		parts = parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,250 @@
+package cacheremover
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexVersion changes whenever the layout of the index file changes
+const indexVersion = 1
+
+// indexFileName is the file inside the index directory
+const indexFileName = "dirs.gob"
+
+// racyWindow is how recently a directory may have been modified and still
+// be indexed. A directory changed again within the timestamp granularity
+// of its filesystem would keep its modification time.
+const racyWindow = 2 * time.Second
+
+// ScanIndex remembers the directories inside cache directories from one
+// scan to the next, so that a rescan does not read again the ones whose
+// modification time and inode are unchanged. Only their subdirectories are
+// examined; their files are counted from the index. A file rewritten in
+// place does not change its directory, so its new size is only noticed
+// once something is added to, removed from or renamed in that directory.
+type ScanIndex struct {
+	path   string
+	config string // Fingerprint of the configuration the index was built with
+	cwd    string // Relative paths are stored relative to this
+
+	mu      sync.Mutex
+	dirs    map[string]indexedDir
+	visited map[string]bool // Directories seen since the last Save
+	roots   []string        // Directories walked since the last Save
+}
+
+// indexedDir is what the index remembers about one directory
+type indexedDir struct {
+	ModTime  int64 // Nanoseconds since the epoch
+	Dev, Ino uint64
+	// Size of the files that are not directories: Reclaimable and Apparent
+	// count files with a single link, Linked the others
+	Reclaimable int64
+	Apparent    int64
+	Linked      []indexedLink
+	Dirs        []string // Names of the subdirectories
+}
+
+// indexedLink is a file with several hard links in an indexedDir
+type indexedLink struct {
+	Dev, Ino        uint64
+	Allocated, Size int64
+	Links, Seen     uint64
+}
+
+// indexFile is the on-disk form of a ScanIndex. It is only read by this
+// package, so it uses gob, which loads large indexes much faster than JSON.
+type indexFile struct {
+	Version int
+	Config  string
+	Dirs    map[string]indexedDir
+}
+
+// DefaultIndexDir returns the index directory under ~/.cache-remover
+func DefaultIndexDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache-remover", "index")
+}
+
+// OpenScanIndex loads the index stored in dir. An index that is missing,
+// unreadable as an index or built with another configuration starts out
+// empty; only failing to read an existing file is an error.
+func OpenScanIndex(dir string, config *Config) (*ScanIndex, error) {
+	cwd, _ := os.Getwd()
+	x := &ScanIndex{
+		path:    filepath.Join(dir, indexFileName),
+		config:  configFingerprint(config),
+		cwd:     cwd,
+		dirs:    make(map[string]indexedDir),
+		visited: make(map[string]bool),
+	}
+
+	f, err := os.Open(x.path)
+	if os.IsNotExist(err) {
+		return x, nil
+	}
+	if err != nil {
+		return x, err
+	}
+	defer f.Close()
+
+	var file indexFile
+	if err := gob.NewDecoder(f).Decode(&file); err != nil {
+		return x, nil
+	}
+	if file.Version == indexVersion && file.Config == x.config && file.Dirs != nil {
+		x.dirs = file.Dirs
+	}
+	return x, nil
+}
+
+// configFingerprint identifies a configuration. Any change to it, even
+// one that does not affect sizes, invalidates the index.
+func configFingerprint(config *Config) string {
+	data, _ := json.Marshal(config)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Path returns the index file location
+func (x *ScanIndex) Path() string {
+	return x.path
+}
+
+// Save writes the index. Directories below the roots walked since the
+// last Save that were not seen again are dropped, since they no longer
+// exist or no longer belong to a cache directory. Save does nothing on a
+// nil index.
+func (x *ScanIndex) Save() error {
+	if x == nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for path := range x.dirs {
+		if !x.visited[path] && x.walked(path) {
+			delete(x.dirs, path)
+		}
+	}
+	x.visited = make(map[string]bool)
+	x.roots = nil
+
+	if err := os.MkdirAll(filepath.Dir(x.path), 0700); err != nil {
+		return err
+	}
+	// Write a new file and rename it, so that a concurrent run never reads
+	// a partial index
+	f, err := os.CreateTemp(filepath.Dir(x.path), indexFileName+".*")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(indexFile{Version: indexVersion, Config: x.config, Dirs: x.dirs})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), x.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// walked reports whether path is at or below a root walked since the last Save
+func (x *ScanIndex) walked(path string) bool {
+	for _, root := range x.roots {
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *ScanIndex) key(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(x.cwd, path)
+}
+
+// walk records that a walk starts at root
+func (x *ScanIndex) walk(root string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.roots = append(x.roots, x.key(root))
+}
+
+// lookup returns the indexed directory at path if info, from an lstat of
+// path, shows it unchanged
+func (x *ScanIndex) lookup(path string, info os.FileInfo) (indexedDir, bool) {
+	if x == nil {
+		return indexedDir{}, false
+	}
+	key := x.key(path)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	dir, ok := x.dirs[key]
+	if !ok {
+		return indexedDir{}, false
+	}
+	id, _, _, _ := fileUsage(info)
+	if dir.ModTime != info.ModTime().UnixNano() || dir.Dev != id.dev || dir.Ino != id.ino {
+		return indexedDir{}, false
+	}
+	x.visited[key] = true
+	return dir, true
+}
+
+// store indexes the directory at path, described by info, unless it was
+// modified too recently to tell later changes apart
+func (x *ScanIndex) store(path string, info os.FileInfo, dir indexedDir) {
+	if x == nil || time.Since(info.ModTime()) < racyWindow {
+		return
+	}
+	id, _, _, _ := fileUsage(info)
+	dir.ModTime, dir.Dev, dir.Ino = info.ModTime().UnixNano(), id.dev, id.ino
+
+	key := x.key(path)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.dirs[key] = dir
+	x.visited[key] = true
+}
+
+// newIndexedDir records the files counted by files and the names of the
+// subdirectories of a directory
+func newIndexedDir(files *sizeCounter, dirs []string) indexedDir {
+	dir := indexedDir{Reclaimable: files.reclaimable, Apparent: files.apparent, Dirs: dirs}
+	for id, f := range files.linked {
+		dir.Linked = append(dir.Linked, indexedLink{
+			Dev: id.dev, Ino: id.ino,
+			Allocated: f.allocated, Size: f.size,
+			Links: f.links, Seen: f.seen,
+		})
+	}
+	return dir
+}
+
+// files returns a counter of the files of the directory
+func (dir indexedDir) files() *sizeCounter {
+	c := &sizeCounter{reclaimable: dir.Reclaimable, apparent: dir.Apparent}
+	if len(dir.Linked) > 0 {
+		c.linked = make(map[fileID]*linkedFile, len(dir.Linked))
+	}
+	for _, f := range dir.Linked {
+		c.linked[fileID{dev: f.Dev, ino: f.Ino}] = &linkedFile{allocated: f.Allocated, size: f.Size, links: f.Links, seen: f.Seen}
+	}
+	return c
+}
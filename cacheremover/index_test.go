@@ -0,0 +1,120 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ageDirs sets the modification time of every directory below root to
+// the same date in the past, so that the index does not consider them too
+// recent and the unchanged ones keep matching it
+func ageDirs(t *testing.T, root string) {
+	t.Helper()
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chtimes(path, old, old)
+		}
+		return nil
+	})
+}
+
+// scanNodeModules scans root with a freshly loaded index and returns the
+// size found for its node_modules
+func scanNodeModules(t *testing.T, root, indexDir string) (int64, *ScanIndex) {
+	t.Helper()
+	scanner := newTestScanner()
+	index, err := OpenScanIndex(indexDir, scanner.Config())
+	if err != nil {
+		t.Fatalf("OpenScanIndex failed: %v", err)
+	}
+	scanner.Index = index
+	projects, err := scanner.Scan(context.Background(), root)
+	if err != nil || len(projects) != 1 || len(projects[0].Items) != 1 {
+		t.Fatalf("Expected one project with node_modules, got %+v (%v)", projects, err)
+	}
+	if err := index.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return projects[0].Items[0].ApparentSize, index
+}
+
+func TestScanIndexReusesUnchangedDirectories(t *testing.T) {
+	root := t.TempDir()
+	indexDir := t.TempDir()
+	writeFiles(t, root, map[string]string{"app/package.json": `{}`})
+	writeRandom(t, filepath.Join(root, "app/node_modules/a/package.json"), 1000)
+	writeRandom(t, filepath.Join(root, "app/node_modules/b/lib/b.js"), 2000)
+	ageDirs(t, root)
+
+	size, _ := scanNodeModules(t, root, indexDir)
+	if size != 3000 {
+		t.Fatalf("Expected 3000 bytes, got %d", size)
+	}
+
+	// Rewriting a file in place leaves its directory unchanged, so the
+	// indexed size is used instead of reading the directory again
+	writeRandom(t, filepath.Join(root, "app/node_modules/a/package.json"), 1500)
+	ageDirs(t, root)
+	if size, _ := scanNodeModules(t, root, indexDir); size != 3000 {
+		t.Errorf("Expected the indexed 3000 bytes, got %d", size)
+	}
+
+	// Adding a file changes the directory, which is read again
+	writeRandom(t, filepath.Join(root, "app/node_modules/a/extra.js"), 100)
+	if size, _ := scanNodeModules(t, root, indexDir); size != 3600 {
+		t.Errorf("Expected 3600 bytes after the change, got %d", size)
+	}
+}
+
+func TestScanIndexDropsRemovedDirectories(t *testing.T) {
+	root := t.TempDir()
+	indexDir := t.TempDir()
+	writeFiles(t, root, map[string]string{"app/package.json": `{}`})
+	writeRandom(t, filepath.Join(root, "app/node_modules/a/package.json"), 1000)
+	writeRandom(t, filepath.Join(root, "app/node_modules/b/b.js"), 2000)
+	ageDirs(t, root)
+
+	_, index := scanNodeModules(t, root, indexDir)
+	removed := filepath.Join(root, "app/node_modules/b")
+	if _, ok := index.dirs[removed]; !ok {
+		t.Fatalf("Expected %s in the index, got %v", removed, index.dirs)
+	}
+
+	os.RemoveAll(removed)
+	ageDirs(t, root)
+	size, index := scanNodeModules(t, root, indexDir)
+	if size != 1000 {
+		t.Errorf("Expected 1000 bytes after removing b, got %d", size)
+	}
+	if _, ok := index.dirs[removed]; ok {
+		t.Errorf("Expected %s to be dropped from the index", removed)
+	}
+	if len(index.dirs) != 2 {
+		t.Errorf("Expected node_modules and node_modules/a indexed, got %d directories", len(index.dirs))
+	}
+}
+
+func TestScanIndexConfigChange(t *testing.T) {
+	root := t.TempDir()
+	indexDir := t.TempDir()
+	writeFiles(t, root, map[string]string{"app/package.json": `{}`})
+	writeRandom(t, filepath.Join(root, "app/node_modules/a/package.json"), 1000)
+	ageDirs(t, root)
+	scanNodeModules(t, root, indexDir)
+
+	config := DefaultConfig()
+	index, err := OpenScanIndex(indexDir, &config)
+	if err != nil || len(index.dirs) == 0 {
+		t.Fatalf("Expected the index to load, got %d directories (%v)", len(index.dirs), err)
+	}
+
+	config.Settings.MaxDepth++
+	index, err = OpenScanIndex(indexDir, &config)
+	if err != nil || len(index.dirs) != 0 {
+		t.Errorf("Expected an empty index after a configuration change, got %d directories (%v)", len(index.dirs), err)
+	}
+}
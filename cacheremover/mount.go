@@ -0,0 +1,111 @@
+package cacheremover
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// filesystem identifies the filesystem a walk started on, so that mount
+// points below it can be recognised
+type filesystem struct {
+	dev   uint64
+	known bool
+}
+
+// filesystemOf returns the filesystem holding path, following a symlink
+// at path itself
+func filesystemOf(path string) filesystem {
+	info, err := os.Stat(path)
+	if err != nil {
+		return filesystem{}
+	}
+	return filesystemFor(info)
+}
+
+// filesystemFor returns the filesystem holding the file described by info
+func filesystemFor(info os.FileInfo) filesystem {
+	dev, ok := deviceOf(info)
+	return filesystem{dev: dev, known: ok}
+}
+
+// contains reports whether the file described by info is on this
+// filesystem. It reports true when that cannot be determined.
+func (fs filesystem) contains(info os.FileInfo) bool {
+	if !fs.known {
+		return true
+	}
+	dev, ok := deviceOf(info)
+	return !ok || dev == fs.dev
+}
+
+// mountPoints lists the mount points of the system. It is a variable so
+// that tests can simulate mounts.
+var mountPoints = systemMountPoints
+
+// mountPointsIn returns the mount points at root and below it, joined onto
+// root the way walks and removals build paths. Bind mounts share the device
+// of the directory they mirror, so only this list tells them apart.
+func mountPointsIn(root string) map[string]bool {
+	points := mountPoints()
+	if len(points) == 0 {
+		return nil
+	}
+	real, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	if resolved, err := filepath.EvalSymlinks(real); err == nil {
+		real = resolved
+	}
+
+	var in map[string]bool
+	for _, point := range points {
+		rel, err := filepath.Rel(real, point)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if in == nil {
+			in = make(map[string]bool)
+		}
+		in[filepath.Join(root, rel)] = true
+	}
+	return in
+}
+
+// parseMountInfo returns the mount points listed in the format of
+// /proc/self/mountinfo, whose fifth field is the mount point with spaces,
+// tabs, newlines and backslashes escaped as octal numbers
+func parseMountInfo(r io.Reader) []string {
+	var points []string
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		points = append(points, unescapeOctal(fields[4]))
+	}
+	return points
+}
+
+func unescapeOctal(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,15 @@
+//go:build linux
+
+package cacheremover
+
+import "os"
+
+// systemMountPoints reads the mount points of the process's mount namespace
+func systemMountPoints() []string {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	return parseMountInfo(f)
+}
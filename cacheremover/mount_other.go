@@ -0,0 +1,9 @@
+//go:build !linux
+
+package cacheremover
+
+// systemMountPoints is only implemented on Linux. Elsewhere mount points
+// are recognised by their device number alone.
+func systemMountPoints() []string {
+	return nil
+}
@@ -0,0 +1,184 @@
+package cacheremover
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pattern is a compiled indicator, directory or file pattern from the
+// configuration. Patterns are doublestar-style globs: "*", "?" and "[...]"
+// match within one path segment and a "**" segment matches any number of
+// segments. A "re:" prefix introduces a regular expression instead, which is
+// matched against the slash-separated path relative to the project.
+type pattern struct {
+	raw      string
+	segments []string       // Glob segments; nil for regular expressions
+	re       *regexp.Regexp // Set for "re:" patterns
+}
+
+func compilePattern(raw string) (*pattern, error) {
+	if expr, ok := strings.CutPrefix(raw, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", raw, err)
+		}
+		return &pattern{raw: raw, re: re}, nil
+	}
+
+	clean := strings.TrimSuffix(strings.TrimPrefix(raw, "./"), "/")
+	if clean == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	segments := strings.Split(clean, "/")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid pattern %q: empty path segment", raw)
+		}
+		if segment != "**" && strings.Contains(segment, "**") {
+			return nil, fmt.Errorf("invalid pattern %q: ** must be a whole path segment", raw)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", raw, err)
+		}
+	}
+	return &pattern{raw: raw, segments: segments}, nil
+}
+
+// compilePatterns compiles every pattern in raw, skipping invalid ones.
+// Config.Validate reports those up front.
+func compilePatterns(raw []string) []*pattern {
+	var patterns []*pattern
+	for _, r := range raw {
+		if p, err := compilePattern(r); err == nil {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// isPath reports whether the pattern must be matched against a relative
+// path rather than a single name
+func (p *pattern) isPath() bool {
+	return p.re != nil || len(p.segments) > 1
+}
+
+// isLiteral reports whether the pattern is a plain name without wildcards
+func (p *pattern) isLiteral() bool {
+	return !p.isPath() && !strings.ContainsAny(p.segments[0], `*?[\`)
+}
+
+// matchName matches a single file or directory name against a name pattern
+func (p *pattern) matchName(name string) bool {
+	if p.isPath() {
+		return false
+	}
+	ok, _ := path.Match(p.segments[0], name)
+	return ok
+}
+
+// matchPath matches a slash-separated path relative to the project root
+func (p *pattern) matchPath(rel string) bool {
+	if p.re != nil {
+		return p.re.MatchString(rel)
+	}
+	return matchSegments(p.segments, strings.Split(rel, "/"))
+}
+
+// literalPath returns the pattern as a relative file path when it contains
+// no wildcards
+func (p *pattern) literalPath() (string, bool) {
+	if p.re != nil {
+		return "", false
+	}
+	for _, segment := range p.segments {
+		if strings.ContainsAny(segment, `*?[\`) {
+			return "", false
+		}
+	}
+	return filepath.Join(p.segments...), true
+}
+
+// matchDir matches a directory of the project at root: name patterns by
+// the directory name, path patterns by its path relative to root
+func (p *pattern) matchDir(root, dirPath string) bool {
+	if !p.isPath() {
+		return p.matchName(filepath.Base(dirPath))
+	}
+	return p.matchPath(relSlash(root, dirPath))
+}
+
+// relSlash returns the slash-separated path of target relative to root
+func relSlash(root, target string) string {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// existsIn reports whether any path below dir matches the pattern. Only
+// glob patterns without "**" are supported, so that checking a directory
+// never walks its whole subtree.
+func (p *pattern) existsIn(dir string) bool {
+	if p.re != nil {
+		return false
+	}
+	return globExists(dir, p.segments)
+}
+
+// existsAmong is existsIn for a directory whose entries, sorted by name as
+// os.ReadDir returns them, have been read already. Only patterns reaching
+// below the directory need further lookups.
+func (p *pattern) existsAmong(dir string, entries []os.DirEntry) bool {
+	if p.isPath() {
+		return p.existsIn(dir)
+	}
+	name := p.segments[0]
+	if p.isLiteral() {
+		i := sort.Search(len(entries), func(i int) bool { return entries[i].Name() >= name })
+		return i < len(entries) && entries[i].Name() == name
+	}
+	for _, entry := range entries {
+		if ok, _ := path.Match(name, entry.Name()); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func globExists(dir string, segments []string) bool {
+	segment := segments[0]
+	rest := segments[1:]
+
+	if !strings.ContainsAny(segment, `*?[\`) {
+		next := filepath.Join(dir, segment)
+		if len(rest) == 0 {
+			_, err := os.Stat(next)
+			return err == nil
+		}
+		return globExists(next, rest)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if ok, _ := path.Match(segment, entry.Name()); !ok {
+			continue
+		}
+		if len(rest) == 0 {
+			return true
+		}
+		if entry.IsDir() && globExists(filepath.Join(dir, entry.Name()), rest) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	for _, raw := range []string{"", "a//b", "foo**", "[", "re:("} {
+		if _, err := compilePattern(raw); err == nil {
+			t.Errorf("compilePattern(%q) should fail", raw)
+		}
+	}
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"cmake-build-*", "cmake-build-debug", true},
+		{"cmake-build-*", "cmake-build", false},
+		{"*.egg-info", "mypkg.egg-info", true},
+		{"**/bin/Debug", "bin/Debug", true},
+		{"**/bin/Debug", "src/App/bin/Debug", true},
+		{"**/bin/Debug", "src/App/bin/Release", false},
+		{"./target/", "target", true},
+		{"re:^build-[0-9]+$", "build-42", true},
+		{"re:^build-[0-9]+$", "build-x", false},
+	}
+	for _, tt := range tests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q): %v", tt.pattern, err)
+		}
+		if got := p.matchPath(tt.path); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGlobPatterns(t *testing.T) {
+	scanner := newTestScanner()
+	root := t.TempDir()
+
+	// Xcode projects are only recognisable by their bundle directory
+	os.MkdirAll(filepath.Join(root, "App.xcodeproj"), 0755)
+	if pt := scanner.DetectProjectType(root); pt == nil || pt.Name != "Swift/iOS" {
+		t.Errorf("Expected Swift/iOS project type, got %v", pt)
+	}
+
+	for _, dir := range []string{"cmake-build-debug", "src/App/bin/Debug", "src/App/bin/Release"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+		os.WriteFile(filepath.Join(root, dir, "out.o"), []byte("object"), 0644)
+	}
+	os.WriteFile(filepath.Join(root, "logs-1.txt"), []byte("log"), 0644)
+	os.WriteFile(filepath.Join(root, "notes.txt"), []byte("keep"), 0644)
+
+	config := CacheConfig{
+		Directories: []string{"cmake-build-*", "**/bin/Debug"},
+		Files:       []string{`re:^logs-\d+\.txt$`},
+	}
+	items, err := scanner.FindCacheItems(context.Background(), root, config)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+
+	var found []string
+	for _, item := range items {
+		found = append(found, relSlash(root, item.Path))
+	}
+	want := "cmake-build-debug,src/App/bin/Debug,logs-1.txt"
+	if got := strings.Join(found, ","); got != want {
+		t.Errorf("Expected items %s, got %s", want, got)
+	}
+}
+
+func TestValidateRejectsBadPatterns(t *testing.T) {
+	for _, pt := range []ProjectType{
+		{Name: "Bad", Indicators: []string{"**/pom.xml"}},
+		{Name: "Bad", Indicators: []string{"re:.*"}},
+		{Name: "Bad", Indicators: []string{"x"}, CacheConfig: CacheConfig{Directories: []string{"[build"}}},
+		{Name: "Bad", Indicators: []string{"x"}, CacheConfig: CacheConfig{Files: []string{"re:("}}},
+		{Name: "Bad", Indicators: []string{"x"}, CacheConfig: CacheConfig{Signatures: map[string][]string{"env": {"pyvenv.cfg"}}}},
+		{Name: "Bad", Indicators: []string{"x"}, CacheConfig: CacheConfig{Directories: []string{"env"}, Signatures: map[string][]string{"env": {"**/pyvenv.cfg"}}}},
+	} {
+		config := Config{ProjectTypes: []ProjectType{pt}}
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate should reject %+v", pt)
+		}
+	}
+
+	config := DefaultConfig()
+	if err := config.Validate(); err != nil {
+		t.Errorf("Default config should be valid: %v", err)
+	}
+}
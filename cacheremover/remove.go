@@ -0,0 +1,94 @@
+package cacheremover
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// removeParallelism is how many subdirectories of a tree are removed
+// concurrently
+const removeParallelism = 8
+
+// RemoveReport is the outcome of removing a file or directory tree
+type RemoveReport struct {
+	Files int
+	Dirs  int
+	// BytesFreed is the disk space freed: the blocks of removed directories
+	// and of files whose last link was removed
+	BytesFreed int64
+	// Failures lists the paths that could not be removed and why
+	Failures []*os.PathError
+}
+
+// Err returns a *RemoveError when anything could not be removed
+func (r *RemoveReport) Err() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	return &RemoveError{Failures: r.Failures, BytesFreed: r.BytesFreed}
+}
+
+// RemoveError reports a partial removal
+type RemoveError struct {
+	Failures   []*os.PathError
+	BytesFreed int64
+}
+
+func (e *RemoveError) Error() string {
+	const shown = 3
+	var reasons []string
+	for i, failure := range e.Failures {
+		if i == shown {
+			reasons = append(reasons, fmt.Sprintf("and %d more", len(e.Failures)-shown))
+			break
+		}
+		reasons = append(reasons, failure.Error())
+	}
+	paths := "paths"
+	if len(e.Failures) == 1 {
+		paths = "path"
+	}
+	return fmt.Sprintf("%d %s could not be removed: %s", len(e.Failures), paths, strings.Join(reasons, "; "))
+}
+
+// RemoveAll removes path and everything below it. Symbolic links are
+// removed, never followed, mount points are left alone and missing write
+// permissions are fixed along the way. Unlike os.RemoveAll it carries on
+// past failures and reports every path it could not remove.
+func RemoveAll(path string) *RemoveReport {
+	r := &remover{sem: make(chan struct{}, removeParallelism)}
+	r.removeAll(path)
+	return &r.report
+}
+
+// remover collects the outcome of a RemoveAll call across its goroutines
+type remover struct {
+	sem    chan struct{}
+	dev    uint64          // Filesystem of the parent of the path being removed
+	mounts map[string]bool // Mount points at or below the path, see mountPointsIn
+
+	mu     sync.Mutex
+	report RemoveReport
+}
+
+func (r *remover) removedFile(size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Files++
+	r.report.BytesFreed += size
+}
+
+func (r *remover) removedDir(size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Dirs++
+	r.report.BytesFreed += size
+}
+
+func (r *remover) fail(op, path string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Failures = append(r.report.Failures, &os.PathError{Op: op, Path: path, Err: err})
+}
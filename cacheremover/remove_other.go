@@ -0,0 +1,49 @@
+//go:build !unix
+
+package cacheremover
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// removeAll removes the tree one path at a time; platforms without the
+// *at system calls cannot pin the directories being removed
+func (r *remover) removeAll(path string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			r.fail("lstat", path, err)
+		}
+		return
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if os.IsPermission(err) {
+			os.Chmod(path, info.Mode().Perm()|0700)
+			entries, err = os.ReadDir(path)
+		}
+		if err != nil {
+			r.fail("readdir", path, err)
+		}
+		for _, entry := range entries {
+			r.removeAll(filepath.Join(path, entry.Name()))
+		}
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		// Read-only files cannot be deleted on Windows
+		os.Chmod(path, info.Mode().Perm()|0200)
+		err = os.Remove(path)
+	}
+	switch {
+	case err == nil && info.IsDir():
+		r.removedDir(0)
+	case err == nil:
+		r.removedFile(info.Size())
+	case !os.IsNotExist(err):
+		r.fail("remove", path, err)
+	}
+}
@@ -0,0 +1,116 @@
+package cacheremover
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRemoveAllReadOnlyTree(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "node_modules")
+	files := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("pkg%d/lib/index.js", i)] = "module.exports = 1"
+		files[fmt.Sprintf("pkg%d/package.json", i)] = "{}"
+	}
+	writeFiles(t, root, files)
+
+	// Read-only files in read-only directories, like the Go module cache
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if !info.IsDir() {
+			os.Chmod(path, 0444)
+		}
+		return nil
+	})
+	var dirs []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if info.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chmod(dirs[i], 0555)
+	}
+
+	size, _ := dirSize(root)
+	report := RemoveAll(root)
+	if err := report.Err(); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if report.Files != 40 || report.Dirs != 41 || report.BytesFreed != size {
+		t.Errorf("Expected 40 files, 41 directories and %d bytes, got %+v", size, report)
+	}
+	if _, err := os.Lstat(root); !os.IsNotExist(err) {
+		t.Error("Tree should be removed")
+	}
+}
+
+func TestRemoveAllKeepsSymlinkTargets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	tempDir := t.TempDir()
+	outside := filepath.Join(tempDir, "outside")
+	writeFiles(t, outside, map[string]string{"keep.txt": "precious"})
+
+	root := filepath.Join(tempDir, "target")
+	writeFiles(t, root, map[string]string{"debug/app": "binary"})
+	os.Symlink(outside, filepath.Join(root, "debug", "linked-dir"))
+	os.Symlink(filepath.Join(outside, "keep.txt"), filepath.Join(root, "linked-file"))
+
+	if err := RemoveAll(root).Err(); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if _, err := os.Lstat(root); !os.IsNotExist(err) {
+		t.Error("Tree should be removed")
+	}
+	if data, err := os.ReadFile(filepath.Join(outside, "keep.txt")); err != nil || string(data) != "precious" {
+		t.Errorf("Symlink target should be untouched: %q, %v", data, err)
+	}
+
+	// A symlinked cache item is unlinked, not emptied
+	link := filepath.Join(tempDir, "node_modules")
+	os.Symlink(outside, link)
+	if err := RemoveAll(link).Err(); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "keep.txt")); err != nil {
+		t.Errorf("Symlink target should be untouched: %v", err)
+	}
+}
+
+func TestRemoveAllMissing(t *testing.T) {
+	report := RemoveAll(filepath.Join(t.TempDir(), "missing"))
+	if report.Err() != nil || report.Files != 0 {
+		t.Errorf("Removing a missing path should do nothing, got %+v", report)
+	}
+}
+
+func TestRemoveAllReportsFailures(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("needs a directory the test cannot write to")
+	}
+	parent := filepath.Join(t.TempDir(), "locked")
+	root := filepath.Join(parent, "cache")
+	writeFiles(t, root, map[string]string{"a.bin": "1234", "b.bin": "5678"})
+
+	// The contents can be removed, the directory itself cannot
+	os.Chmod(parent, 0555)
+	defer os.Chmod(parent, 0755)
+
+	report := RemoveAll(root)
+	if report.Files != 2 || report.BytesFreed == 0 {
+		t.Errorf("Expected the files to be removed, got %+v", report)
+	}
+	var removeErr *RemoveError
+	if !errors.As(report.Err(), &removeErr) || len(removeErr.Failures) != 1 || removeErr.Failures[0].Path != root {
+		t.Fatalf("Expected one failure for %s, got %v", root, report.Err())
+	}
+	if !errors.Is(removeErr.Failures[0].Err, os.ErrPermission) {
+		t.Errorf("Expected a permission error, got %v", removeErr.Failures[0].Err)
+	}
+}
@@ -0,0 +1,146 @@
+//go:build unix
+
+package cacheremover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// errMountPoint is reported for directories on another filesystem and for
+// bind mounts, whose contents are never removed
+var errMountPoint = errors.New("mount point, not removed")
+
+// removeAll works relative to open directory descriptors, so a directory
+// swapped for a symlink while it is being removed cannot redirect the
+// removal elsewhere
+func (r *remover) removeAll(path string) {
+	path = filepath.Clean(path)
+	// Symlinks above path are followed like everywhere else
+	parent, err := openDir(unix.AT_FDCWD, filepath.Dir(path), 0)
+	if err != nil {
+		if !errors.Is(err, unix.ENOENT) {
+			r.fail("open", filepath.Dir(path), err)
+		}
+		return
+	}
+	defer unix.Close(parent)
+
+	// A path that is itself a mount point is left alone too
+	var st unix.Stat_t
+	if err := unix.Fstat(parent, &st); err != nil {
+		r.fail("stat", filepath.Dir(path), err)
+		return
+	}
+	r.dev = uint64(st.Dev)
+	r.mounts = mountPointsIn(path)
+	r.remove(parent, filepath.Base(path), path)
+}
+
+// remove removes the entry name of the directory dirfd
+func (r *remover) remove(dirfd int, name, path string) {
+	var st unix.Stat_t
+	if err := unix.Fstatat(dirfd, name, &st, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		if !errors.Is(err, unix.ENOENT) {
+			r.fail("lstat", path, err)
+		}
+		return
+	}
+
+	if st.Mode&unix.S_IFMT == unix.S_IFDIR {
+		if uint64(st.Dev) != r.dev || r.mounts[path] {
+			r.fail("remove", path, errMountPoint)
+			return
+		}
+		r.removeDir(dirfd, name, path, &st)
+		return
+	}
+	if err := unix.Unlinkat(dirfd, name, 0); err != nil {
+		if !errors.Is(err, unix.ENOENT) {
+			r.fail("unlink", path, err)
+		}
+		return
+	}
+	// The data is freed with the last link; removing the other links of a
+	// file in the same tree counts it then
+	if st.Nlink <= 1 {
+		r.removedFile(int64(st.Blocks) * 512)
+	} else {
+		r.removedFile(0)
+	}
+}
+
+// removeDir empties and removes a directory. Subdirectories are handed to
+// other goroutines while fewer than removeParallelism are busy.
+func (r *remover) removeDir(dirfd int, name, path string, st *unix.Stat_t) {
+	fd, err := openDir(dirfd, name, unix.O_NOFOLLOW)
+	if errors.Is(err, unix.EACCES) {
+		unix.Fchmodat(dirfd, name, uint32(st.Mode)&07777|0700, 0)
+		fd, err = openDir(dirfd, name, unix.O_NOFOLLOW)
+	}
+	if err != nil {
+		r.fail("open", path, err)
+		return
+	}
+
+	// Make sure the directory opened is the one that was examined
+	var opened unix.Stat_t
+	if err := unix.Fstat(fd, &opened); err != nil || opened.Dev != st.Dev || opened.Ino != st.Ino {
+		unix.Close(fd)
+		r.fail("open", path, errors.New("directory was replaced during removal"))
+		return
+	}
+	if st.Mode&0700 != 0700 {
+		unix.Fchmod(fd, uint32(st.Mode)&07777|0700)
+	}
+
+	dir := os.NewFile(uintptr(fd), path)
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		r.fail("readdir", path, err)
+	}
+
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		child, childPath := entry.Name(), filepath.Join(path, entry.Name())
+		if !entry.IsDir() {
+			r.remove(fd, child, childPath)
+			continue
+		}
+		select {
+		case r.sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-r.sem }()
+				r.remove(fd, child, childPath)
+			}()
+		default:
+			r.remove(fd, child, childPath)
+		}
+	}
+	wg.Wait()
+	dir.Close()
+
+	if err := unix.Unlinkat(dirfd, name, unix.AT_REMOVEDIR); err != nil {
+		if !errors.Is(err, unix.ENOENT) {
+			r.fail("rmdir", path, err)
+		}
+		return
+	}
+	r.removedDir(int64(st.Blocks) * 512)
+}
+
+// openDir opens a directory relative to dirfd
+func openDir(dirfd int, name string, flags int) (int, error) {
+	for {
+		fd, err := unix.Openat(dirfd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC|flags, 0)
+		if !errors.Is(err, unix.EINTR) {
+			return fd, err
+		}
+	}
+}
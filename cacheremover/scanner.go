@@ -0,0 +1,395 @@
+// Package cacheremover detects development projects and the cache
+// directories and files they accumulate, and removes them on request.
+//
+// The CLI and the interactive TUI are thin consumers of this package; other
+// tools can import it to reuse the same detection logic without parsing the
+// human-oriented output of the command.
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+type CacheItem struct {
+	Path string `json:"path"`
+	// Size is the disk space removing the item frees: allocated blocks,
+	// counting a hard-linked file once and only when all of its links are
+	// inside the item
+	Size int64 `json:"size"`
+	// ApparentSize is the sum of the file sizes, as ls reports them
+	ApparentSize int64 `json:"apparent_size"`
+	// Type is "directory", "file" or "symlink". Removing a symlink removes
+	// the link, never its target.
+	Type string `json:"type"`
+	// GitStatus is GitIgnored, GitUntracked or GitTracked, or empty outside a git work tree
+	GitStatus string `json:"git_status,omitempty"`
+}
+
+// Project is a detected project together with its cache items
+type Project struct {
+	Path string
+	// Type is the detected type, combined when several types match
+	Type *ProjectType
+	// Types lists the individual types that make up Type
+	Types []*ProjectType
+	// Items and the sizes cover the whole project, including its members
+	Items     []CacheItem
+	TotalSize int64
+	// ApparentSize is the apparent size of the items; see CacheItem
+	ApparentSize int64
+	// Members are the sub-projects of a workspace root such as an npm, pnpm,
+	// Cargo, Gradle or Go workspace. Each cache item belongs to exactly one
+	// member or to the root itself.
+	Members []*Project
+	// LastActivity is when the project was last worked on; only set when
+	// the scanner's MeasureActivity option is enabled
+	LastActivity time.Time
+}
+
+// EventKind identifies the kind of scanner event
+type EventKind int
+
+const (
+	// EventProjectFound is reported for every project directory discovered
+	EventProjectFound EventKind = iota
+	// EventCacheDirSkipped is reported when the walk skips a cache directory
+	EventCacheDirSkipped
+	// EventAccessError is reported when a path cannot be read
+	EventAccessError
+	// EventTrackedSkipped is reported when a cache item is kept because it
+	// contains files tracked by git
+	EventTrackedSkipped
+	// EventExcluded is reported when a directory or cache item is kept
+	// because of an exclude pattern or a .cacheremoverignore file
+	EventExcluded
+	// EventSignatureMismatch is reported when a directory matches a cache
+	// directory name but lacks the contents that identify it as a cache
+	EventSignatureMismatch
+	// EventMountPointSkipped is reported when a directory on another
+	// filesystem is not scanned
+	EventMountPointSkipped
+	// EventProjectScanned is reported by Scan when the cache items of a
+	// project have been found and sized, before the whole scan completes
+	EventProjectScanned
+	// EventGitIndexUnreadable is reported when a cache item is kept because
+	// the git index of its repository cannot be read, so tracked files
+	// cannot be ruled out. Err tells why.
+	EventGitIndexUnreadable
+)
+
+// Event describes something noteworthy that happened during a scan
+type Event struct {
+	Kind EventKind
+	Path string
+	Err  error
+	// Project is the completed project of an EventProjectScanned. Scan
+	// returns a copy of it, so it must not be modified before Scan returns.
+	Project *Project
+}
+
+// Scanner finds projects and their cache items using a Config
+type Scanner struct {
+	config        *Config
+	cacheDirs     map[string]bool // Plain cache directory names
+	cacheDirGlobs []*pattern      // Cache directory name patterns such as "cmake-build-*"
+	indicators    [][]*pattern    // Compiled indicators, by project type index
+
+	// MaxDepth limits how many directory levels below the root are scanned
+	MaxDepth int
+	// SkipHidden prevents descending into directories whose name starts with a dot
+	SkipHidden bool
+	// IncludeTracked keeps cache items that contain files tracked by git.
+	// By default such items are dropped, since a committed "build" or "env"
+	// folder is source code rather than cache.
+	IncludeTracked bool
+	// MeasureActivity makes ScanProject record each project's LastActivity
+	MeasureActivity bool
+	// GitActivity counts the last commit of a project's repository as activity
+	GitActivity bool
+	// OneFileSystem keeps FindProjects from descending into directories on
+	// other filesystems, and on Linux into bind mounts. Mount points inside
+	// a project are never scanned.
+	OneFileSystem bool
+	// Index, if set, lets scans skip reading the directories inside cache
+	// directories that have not changed since an earlier scan
+	Index *ScanIndex
+	// OnEvent, if set, is called for progress and warning events. Calls
+	// never overlap, although they come from several goroutines.
+	OnEvent func(Event)
+	eventMu sync.Mutex
+
+	gitMu    sync.Mutex
+	gitRepos map[string]*gitRepo // Keyed by work tree root
+
+	ignoreMu    sync.Mutex
+	ignoreFiles map[string]*ignoreRules // .cacheremoverignore rules, keyed by directory
+}
+
+// NewScanner creates a Scanner for the given configuration. Invalid
+// patterns never match; Config.Validate reports them.
+func NewScanner(config *Config) *Scanner {
+	s := &Scanner{
+		config:        config,
+		cacheDirs:     make(map[string]bool),
+		MaxDepth:      config.Settings.MaxDepth,
+		OneFileSystem: true,
+	}
+
+	for _, projectType := range config.ProjectTypes {
+		s.indicators = append(s.indicators, compilePatterns(projectType.Indicators))
+
+		// Path patterns depend on the project root, so only names can be
+		// recognised without knowing which project a directory belongs to
+		for _, cacheDir := range compilePatterns(projectType.CacheConfig.Directories) {
+			switch {
+			case cacheDir.isLiteral():
+				s.cacheDirs[cacheDir.segments[0]] = true
+			case !cacheDir.isPath():
+				s.cacheDirGlobs = append(s.cacheDirGlobs, cacheDir)
+			}
+		}
+	}
+
+	return s
+}
+
+// Config returns the configuration the scanner was created with
+func (s *Scanner) Config() *Config {
+	return s.config
+}
+
+func (s *Scanner) emit(kind EventKind, path string, err error) {
+	s.emitEvent(Event{Kind: kind, Path: path, Err: err})
+}
+
+func (s *Scanner) emitEvent(event Event) {
+	if s.OnEvent != nil {
+		s.eventMu.Lock()
+		defer s.eventMu.Unlock()
+		s.OnEvent(event)
+	}
+}
+
+// IsCacheDirectory reports whether dirName matches any cache directory name
+// or name pattern. Path patterns such as "**/bin/Debug" are not considered.
+func (s *Scanner) IsCacheDirectory(dirName string) bool {
+	if s.cacheDirs[dirName] {
+		return true
+	}
+	for _, glob := range s.cacheDirGlobs {
+		if glob.matchName(dirName) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProjectDirectory reports whether dir contains an indicator of any project type
+func (s *Scanner) IsProjectDirectory(dir string) bool {
+	return len(s.DetectProjectTypes(dir)) > 0
+}
+
+// DetectProjectTypes returns every project type with an indicator in
+// projectPath, in configuration order
+func (s *Scanner) DetectProjectTypes(projectPath string) []*ProjectType {
+	entries, _ := os.ReadDir(projectPath)
+	return s.detectProjectTypes(projectPath, entries)
+}
+
+// detectProjectTypes implements DetectProjectTypes for a directory whose
+// entries have been read already, so that most indicators are matched
+// without touching the disk again
+func (s *Scanner) detectProjectTypes(dir string, entries []os.DirEntry) []*ProjectType {
+	var types []*ProjectType
+	for i := range s.config.ProjectTypes {
+		for _, indicator := range s.indicators[i] {
+			if indicator.existsAmong(dir, entries) {
+				types = append(types, &s.config.ProjectTypes[i])
+				break
+			}
+		}
+	}
+	return types
+}
+
+// DetectProjectType returns the type of projectPath, or nil if it is not a
+// project. When several types match, as in a repository with both
+// package.json and pyproject.toml, the result combines them: its name
+// joins theirs with "+" and its cache configuration is the union of theirs.
+func (s *Scanner) DetectProjectType(projectPath string) *ProjectType {
+	return CombineProjectTypes(s.DetectProjectTypes(projectPath))
+}
+
+// FindProjects walks rootDir and returns every project directory found.
+// Cache directories are never descended into. Members of a workspace are
+// not returned; ScanProject reports them as part of the workspace root.
+func (s *Scanner) FindProjects(ctx context.Context, rootDir string) ([]string, error) {
+	s.resetCaches()
+	w := s.newWalk(ctx, true, false)
+	task, ok := w.rootTask(rootDir)
+	if !ok {
+		return nil, nil
+	}
+
+	result, err := w.run(task)
+	var projects []string
+	for _, ps := range result.projects {
+		projects = append(projects, ps.path)
+	}
+	return projects, err
+}
+
+// ScanProject detects the type of projectPath and collects its cache items.
+// For a workspace root the members are scanned too. It returns nil if
+// projectPath is not a project.
+func (s *Scanner) ScanProject(ctx context.Context, projectPath string) (*Project, error) {
+	w := s.newWalk(ctx, false, true)
+	entries, _ := os.ReadDir(projectPath)
+	ps := w.newProject(projectPath, entries)
+	if ps == nil {
+		return nil, nil
+	}
+	ps.activity = s.MeasureActivity
+	if err := w.walkProject(ps, true); err != nil {
+		return nil, err
+	}
+	return ps.project, nil
+}
+
+// Scan finds all projects under rootDir and collects their cache items. The
+// tree is walked once: projects are discovered, their items matched and
+// sized in the same pass. Each project is reported with an
+// EventProjectScanned as soon as it is complete.
+func (s *Scanner) Scan(ctx context.Context, rootDir string) ([]Project, error) {
+	s.resetCaches()
+	w := s.newWalk(ctx, true, true)
+	task, ok := w.rootTask(rootDir)
+	if !ok {
+		return nil, nil
+	}
+	s.Index.walk(rootDir)
+
+	result, err := w.run(task)
+	projects := make([]Project, 0, len(result.projects))
+	for _, ps := range result.projects {
+		projects = append(projects, *ps.project)
+	}
+	return projects, err
+}
+
+// resetCaches forgets the repository state and ignore files cached during
+// the previous scan
+func (s *Scanner) resetCaches() {
+	s.gitMu.Lock()
+	s.gitRepos = nil
+	s.gitMu.Unlock()
+	s.ignoreMu.Lock()
+	s.ignoreFiles = nil
+	s.ignoreMu.Unlock()
+}
+
+// FindCacheItems collects the cache directories and files of a project.
+// Items protected by config.Exclude or a .cacheremoverignore file are left out.
+func (s *Scanner) FindCacheItems(ctx context.Context, projectPath string, config CacheConfig) ([]CacheItem, error) {
+	ps := newProjectScan(projectPath, config)
+	ps.project = &Project{Path: projectPath}
+	err := s.newWalk(ctx, false, true).walkProject(ps, true)
+	return ps.project.Items, err
+}
+
+// hasSignature reports whether dirPath contains one of the signature
+// patterns. An empty signature accepts every directory.
+func hasSignature(dirPath string, signature []*pattern) bool {
+	if len(signature) == 0 {
+		return true
+	}
+	for _, marker := range signature {
+		if marker.existsIn(dirPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGitStatus records the git status of each item and drops items that
+// contain tracked files unless IncludeTracked is set. When the index of the
+// repository cannot be read, every item is dropped.
+func (s *Scanner) checkGitStatus(projectPath string, items []CacheItem) []CacheItem {
+	repo, err := s.loadGitRepo(projectPath)
+	if err != nil {
+		if s.IncludeTracked {
+			s.emit(EventAccessError, repo.gitDir, err)
+			return items
+		}
+		for _, item := range items {
+			s.emit(EventGitIndexUnreadable, item.Path, err)
+		}
+		return items[:0]
+	}
+	if repo == nil {
+		return items
+	}
+
+	kept := items[:0]
+	for _, item := range items {
+		item.GitStatus = repo.status(item.Path, item.Type == "directory")
+		if item.GitStatus == GitTracked && !s.IncludeTracked {
+			s.emit(EventTrackedSkipped, item.Path, nil)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// loadGitRepo returns the git work tree containing projectPath with its
+// index read, or nil if there is none. The repository is also returned
+// with the error when its index cannot be read.
+func (s *Scanner) loadGitRepo(projectPath string) (*gitRepo, error) {
+	found := findGitRepo(projectPath)
+	if found == nil {
+		return nil, nil
+	}
+
+	// Projects in the same repository share one parsed index
+	s.gitMu.Lock()
+	if s.gitRepos == nil {
+		s.gitRepos = make(map[string]*gitRepo)
+	}
+	repo, ok := s.gitRepos[found.root]
+	if !ok {
+		repo = found
+		s.gitRepos[found.root] = repo
+	}
+	s.gitMu.Unlock()
+	return repo, repo.refresh()
+}
+
+// newFileItem creates the cache item for a file, or for a symlink, which
+// is removed rather than its target
+func newFileItem(path string, info os.FileInfo) CacheItem {
+	item := CacheItem{Path: path, Type: "file"}
+	if info.Mode()&os.ModeSymlink != 0 {
+		item.Type = "symlink"
+	}
+	item.Size, item.ApparentSize = fileSizes(info)
+	return item
+}
+
+// dirSize returns the space that removing dirPath frees and the apparent
+// size of its files, leaving out mount points. Symlinks count with their
+// own size, not their target's.
+func dirSize(dirPath string) (size, apparent int64) {
+	// Sizing needs none of a scanner's settings
+	w := (&Scanner{}).newWalk(context.Background(), false, false)
+	task, ok := w.rootTask(dirPath)
+	if !ok {
+		return 0, 0
+	}
+	task.size = true
+	result, _ := w.run(task)
+	return result.usage.sizes()
+}
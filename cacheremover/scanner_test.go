@@ -0,0 +1,445 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func newTestScanner() *Scanner {
+	config := DefaultConfig()
+	return NewScanner(&config)
+}
+
+func TestDetectProjectType(t *testing.T) {
+	scanner := newTestScanner()
+
+	// Create a temporary directory for testing
+	tempDir := t.TempDir()
+
+	// Test Node.js project detection
+	packageJSONPath := filepath.Join(tempDir, "package.json")
+	file, err := os.Create(packageJSONPath)
+	if err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	file.Close()
+
+	projectType := scanner.DetectProjectType(tempDir)
+	if projectType == nil || projectType.Name != "Node.js" {
+		t.Errorf("Expected Node.js project type, got %v", projectType)
+	}
+
+	// Clean up
+	os.Remove(packageJSONPath)
+
+	// Test Python project detection
+	requirementsPath := filepath.Join(tempDir, "requirements.txt")
+	file, err = os.Create(requirementsPath)
+	if err != nil {
+		t.Fatalf("Failed to create requirements.txt: %v", err)
+	}
+	file.Close()
+
+	projectType = scanner.DetectProjectType(tempDir)
+	if projectType == nil || projectType.Name != "Python" {
+		t.Errorf("Expected Python project type, got %v", projectType)
+	}
+}
+
+func TestIsProjectDirectory(t *testing.T) {
+	scanner := newTestScanner()
+	tempDir := t.TempDir()
+
+	// Initially should not be a project directory
+	if scanner.IsProjectDirectory(tempDir) {
+		t.Error("Empty directory should not be detected as project")
+	}
+
+	// Create a Go project indicator
+	goModPath := filepath.Join(tempDir, "go.mod")
+	file, err := os.Create(goModPath)
+	if err != nil {
+		t.Fatalf("Failed to create go.mod: %v", err)
+	}
+	file.Close()
+
+	// Now should be detected as project directory
+	if !scanner.IsProjectDirectory(tempDir) {
+		t.Error("Directory with go.mod should be detected as project")
+	}
+}
+
+func TestFindCacheItems(t *testing.T) {
+	scanner := newTestScanner()
+	tempDir := t.TempDir()
+
+	// Create some cache directories and files
+	nodemodulesDir := filepath.Join(tempDir, "node_modules")
+	os.MkdirAll(nodemodulesDir, 0755)
+
+	// Create a file inside node_modules
+	testFile := filepath.Join(nodemodulesDir, "test.js")
+	file, err := os.Create(testFile)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	file.WriteString("console.log('test');")
+	file.Close()
+
+	config := CacheConfig{
+		Directories: []string{"node_modules"},
+		Files:       []string{},
+		Extensions:  []string{},
+	}
+
+	items, err := scanner.FindCacheItems(context.Background(), tempDir, config)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 cache item, got %d", len(items))
+	}
+
+	if items[0].Path != nodemodulesDir {
+		t.Errorf("Expected cache item path %s, got %s", nodemodulesDir, items[0].Path)
+	}
+
+	if items[0].Size <= 0 {
+		t.Error("Cache item should have size > 0")
+	}
+}
+
+func TestCacheDirectorySkipping(t *testing.T) {
+	scanner := newTestScanner()
+	tempDir := t.TempDir()
+
+	// Create nested structure with cache directories
+	nodePath := filepath.Join(tempDir, "project")
+	nodeModulesPath := filepath.Join(nodePath, "node_modules")
+	nestedPath := filepath.Join(nodeModulesPath, "some-package", "node_modules")
+
+	os.MkdirAll(nestedPath, 0755)
+
+	// Create package.json to make it a project
+	packageJSON := filepath.Join(nodePath, "package.json")
+	os.WriteFile(packageJSON, []byte(`{"name": "test"}`), 0644)
+
+	// Create a file deep in nested node_modules
+	testFile := filepath.Join(nestedPath, "test.js")
+	os.WriteFile(testFile, []byte("test content"), 0644)
+
+	var skipped []string
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventCacheDirSkipped {
+			skipped = append(skipped, event.Path)
+		}
+	}
+
+	// Test that we skip descending into cache directories
+	projects, err := scanner.FindProjects(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("FindProjects failed: %v", err)
+	}
+	if len(projects) != 1 {
+		t.Fatalf("Expected 1 project, found %d", len(projects))
+	}
+
+	// The nested node_modules should not cause additional projects to be found
+	if projects[0] != nodePath {
+		t.Errorf("Expected project path %s, got %s", nodePath, projects[0])
+	}
+
+	if len(skipped) != 1 || skipped[0] != nodeModulesPath {
+		t.Errorf("Expected skip event for %s, got %v", nodeModulesPath, skipped)
+	}
+}
+
+func TestFindProjectsCancelled(t *testing.T) {
+	scanner := newTestScanner()
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte("module test\n"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := scanner.FindProjects(ctx, tempDir); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	scanner := newTestScanner()
+	tempDir := t.TempDir()
+
+	projectDir := filepath.Join(tempDir, "app")
+	os.MkdirAll(filepath.Join(projectDir, "node_modules"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "package.json"), []byte(`{}`), 0644)
+	os.WriteFile(filepath.Join(projectDir, "node_modules", ".package-lock.json"), []byte("{}"), 0644)
+
+	projects, err := scanner.Scan(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(projects) != 1 {
+		t.Fatalf("Expected 1 project, got %d", len(projects))
+	}
+
+	project := projects[0]
+	if project.Type.Name != "Node.js" {
+		t.Errorf("Expected Node.js project, got %s", project.Type.Name)
+	}
+	if len(project.Items) != 1 || project.TotalSize != project.Items[0].Size {
+		t.Errorf("Unexpected cache items: %+v (total %d)", project.Items, project.TotalSize)
+	}
+}
+
+func TestMultiTypeDetection(t *testing.T) {
+	scanner := newTestScanner()
+	tempDir := t.TempDir()
+
+	for _, name := range []string{"package.json", "pyproject.toml", "build.gradle"} {
+		os.WriteFile(filepath.Join(tempDir, name), []byte{}, 0644)
+	}
+	// "build" is a cache directory of both Node.js and Gradle
+	for _, dir := range []string{"node_modules", "build", "__pycache__", ".gradle"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+		os.WriteFile(filepath.Join(tempDir, dir, "cached"), []byte("data"), 0644)
+	}
+	os.WriteFile(filepath.Join(tempDir, "node_modules", ".package-lock.json"), []byte("{}"), 0644)
+
+	project, err := scanner.ScanProject(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("ScanProject failed: %v", err)
+	}
+	if project == nil || project.Type.Name != "Node.js+Python+Gradle" {
+		t.Fatalf("Expected combined Node.js+Python+Gradle type, got %v", project)
+	}
+	if len(project.Types) != 3 {
+		t.Errorf("Expected 3 individual types, got %d", len(project.Types))
+	}
+	if len(project.Items) != 4 {
+		t.Errorf("Expected 4 cache items without duplicates, got %d: %v", len(project.Items), project.Items)
+	}
+}
+
+func TestSignatureVerification(t *testing.T) {
+	scanner := newTestScanner()
+	tempDir := t.TempDir()
+
+	// A real virtual environment, a source folder named "testing" and an
+	// unverified cache directory
+	files := map[string]string{
+		"venv/pyvenv.cfg":          "home = /usr/bin",
+		"venv/lib/site.py":         "pass",
+		"testing/test_api.py":      "def test(): pass",
+		"__pycache__/main.pyc":     "bytecode",
+		"development/bin/activate": "# activate",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	var mismatched []string
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventSignatureMismatch {
+			mismatched = append(mismatched, filepath.Base(event.Path))
+		}
+	}
+
+	config := DefaultConfig().ProjectTypes[1].CacheConfig
+	items, err := scanner.FindCacheItems(context.Background(), tempDir, config)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+
+	if got, want := itemPaths(tempDir, items), "__pycache__,development,venv"; got != want {
+		t.Errorf("Expected items %s, got %s", want, got)
+	}
+	if len(mismatched) != 1 || mismatched[0] != "testing" {
+		t.Errorf("Expected a signature mismatch for testing, got %v", mismatched)
+	}
+}
+
+func TestNodeModulesSignature(t *testing.T) {
+	scanner := newTestScanner()
+	root := t.TempDir()
+	// Installed by pnpm, holding only a scoped package, and a source
+	// folder that relies on Node's module resolution
+	writeFiles(t, root, map[string]string{
+		"node_modules/.modules.yaml":             "layoutVersion: 5",
+		"lib/node_modules/@acme/ui/package.json": `{}`,
+		"src/node_modules/helpers/format.js":     "module.exports = {}",
+	})
+
+	var mismatched []string
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventSignatureMismatch {
+			mismatched = append(mismatched, relSlash(root, event.Path))
+		}
+	}
+
+	items, err := scanner.FindCacheItems(context.Background(), root, DefaultConfig().ProjectTypes[0].CacheConfig)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+	if got, want := itemPaths(root, items), "lib/node_modules,node_modules"; got != want {
+		t.Errorf("Expected items %s, got %s", want, got)
+	}
+	if len(mismatched) != 1 || mismatched[0] != "src/node_modules" {
+		t.Errorf("Expected a signature mismatch for src/node_modules, got %v", mismatched)
+	}
+}
+
+func TestCombineSignatures(t *testing.T) {
+	maven := &ProjectType{Name: "Java/Maven", CacheConfig: CacheConfig{Directories: []string{"target"}}}
+	rust := &ProjectType{Name: "Rust", CacheConfig: CacheConfig{
+		Directories: []string{"target"},
+		Signatures:  map[string][]string{"target": {"CACHEDIR.TAG"}},
+	}}
+
+	if combined := CombineProjectTypes([]*ProjectType{rust, rust}); len(combined.CacheConfig.Signatures["target"]) != 1 {
+		t.Errorf("Expected the target signature to be kept, got %v", combined.CacheConfig.Signatures)
+	}
+	// Maven removes target without verification, so the combination must too
+	if combined := CombineProjectTypes([]*ProjectType{maven, rust}); combined.CacheConfig.Signatures != nil {
+		t.Errorf("Expected no signatures for Java/Maven+Rust, got %v", combined.CacheConfig.Signatures)
+	}
+}
+
+func TestSymlinkedCacheItems(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	tempDir := t.TempDir()
+	store := filepath.Join(tempDir, "store")
+	writeFiles(t, store, map[string]string{"react/package.json": strings.Repeat("x", 1000)})
+
+	project := filepath.Join(tempDir, "app")
+	writeFiles(t, project, map[string]string{
+		"package.json": "{}",
+		"dist/main.js": "bundle",
+		"src/index.js": "source",
+	})
+	// A pnpm-style node_modules linked to a shared store, and a link
+	// inside a real cache directory
+	os.Symlink(store, filepath.Join(project, "node_modules"))
+	os.Symlink(store, filepath.Join(project, "dist", "vendor"))
+
+	scanner := newTestScanner()
+	items, err := scanner.FindCacheItems(context.Background(), project, scanner.DetectProjectType(project).CacheConfig)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+	types := make(map[string]string)
+	for _, item := range items {
+		types[filepath.Base(item.Path)] = item.Type
+		if item.ApparentSize >= 1000 {
+			t.Errorf("%s counts the size of a symlink target: %d", item.Path, item.ApparentSize)
+		}
+	}
+	if types["node_modules"] != "symlink" || types["dist"] != "directory" {
+		t.Fatalf("Expected a symlink and a directory, got %v", types)
+	}
+
+	result, err := newTestCleaner().Clean(context.Background(), items)
+	if err != nil || len(result.Failed) != 0 {
+		t.Fatalf("Clean failed: %v, %v", err, result.Failed)
+	}
+	if _, err := os.Lstat(filepath.Join(project, "node_modules")); !os.IsNotExist(err) {
+		t.Error("The node_modules link should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(store, "react", "package.json")); err != nil {
+		t.Errorf("The store behind the links should be untouched: %v", err)
+	}
+}
+
+func TestFindProjectsSkipsMountPoints(t *testing.T) {
+	// /proc is a separate filesystem wherever it exists
+	root, err := os.Stat("/")
+	if err != nil {
+		t.Skip("no root directory")
+	}
+	proc, err := os.Stat("/proc")
+	if err != nil || filesystemOf("/").contains(proc) || !filesystemOf("/proc").contains(proc) {
+		t.Skip("needs /proc on its own filesystem")
+	}
+	if !filesystemOf("/").contains(root) {
+		t.Fatal("A directory should be on its own filesystem")
+	}
+
+	var skipped []string
+	scanner := newTestScanner()
+	scanner.MaxDepth = 1
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventMountPointSkipped {
+			skipped = append(skipped, event.Path)
+		}
+	}
+	scanner.FindProjects(context.Background(), "/")
+	found := false
+	for _, path := range skipped {
+		found = found || path == "/proc"
+	}
+	if !found {
+		t.Errorf("Expected /proc to be skipped, got %v", skipped)
+	}
+}
+
+func TestBindMountsAreSkipped(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("removal does not check for mount points on Windows")
+	}
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/package.json":                    `{}`,
+		"app/node_modules/.package-lock.json": `{}`,
+		"app/node_modules/shared/data.bin":    "bind mounted",
+		"mirror/package.json":                 `{}`,
+		"mirror/node_modules/x/package.json":  `{}`,
+	})
+	// Bind mounts keep the device number of what they mirror, so only the
+	// mount table tells them apart
+	real, _ := filepath.EvalSymlinks(root)
+	defer func(points func() []string) { mountPoints = points }(mountPoints)
+	mountPoints = func() []string {
+		return []string{"/", filepath.Join(real, "mirror"), filepath.Join(real, "app", "node_modules", "shared")}
+	}
+
+	var skipped []string
+	scanner := newTestScanner()
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventMountPointSkipped {
+			skipped = append(skipped, relSlash(root, event.Path))
+		}
+	}
+	projects, err := scanner.FindProjects(context.Background(), root)
+	if err != nil || len(projects) != 1 || filepath.Base(projects[0]) != "app" {
+		t.Errorf("Expected only app, got %v (%v)", projects, err)
+	}
+	if got := strings.Join(skipped, ","); got != "mirror" {
+		t.Errorf("Expected the mirror mount skipped, got %s", got)
+	}
+
+	report := RemoveAll(filepath.Join(root, "app", "node_modules"))
+	if len(report.Failures) == 0 || !strings.Contains(report.Failures[0].Error(), "mount point") {
+		t.Errorf("Expected the mount point reported, got %v", report.Failures)
+	}
+	if _, err := os.Stat(filepath.Join(root, "app", "node_modules", "shared", "data.bin")); err != nil {
+		t.Errorf("The bind-mounted directory should be untouched: %v", err)
+	}
+}
+
+func TestParseMountInfo(t *testing.T) {
+	info := "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+		"35 22 8:1 /srv/data /home/me/My\\040Projects/data rw,relatime shared:1 - ext4 /dev/sda1 rw\n"
+	got := parseMountInfo(strings.NewReader(info))
+	if want := "/,/home/me/My Projects/data"; strings.Join(got, ",") != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package cacheremover
+
+import "os"
+
+// deviceOf is not supported on this platform, so mount points are not detected
+func deviceOf(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// fileUsage falls back to the apparent size; hard links are not detected
+func fileUsage(info os.FileInfo) (id fileID, allocated int64, links uint64, ok bool) {
+	return fileID{}, info.Size(), 1, false
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package cacheremover
+
+import (
+	"os"
+	"syscall"
+)
+
+// deviceOf returns the ID of the filesystem holding a file
+func deviceOf(info os.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
+
+// fileUsage returns the identity of a file, the bytes allocated to it on
+// disk and its number of hard links
+func fileUsage(info os.FileInfo) (id fileID, allocated int64, links uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, info.Size(), 1, false
+	}
+	// st_blocks is always in 512-byte units, whatever the filesystem block size
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, int64(stat.Blocks) * 512, uint64(stat.Nlink), true
+}
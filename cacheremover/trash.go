@@ -0,0 +1,423 @@
+package cacheremover
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// manifestName is the file that used to record, inside the trash
+// directory itself, the items moved there by this tool
+const manifestName = "cache-remover-manifest.json"
+
+// TrashEntry describes a cache item that was moved to the trash
+type TrashEntry struct {
+	ID           string    `json:"id"`
+	OriginalPath string    `json:"original_path"`
+	Size         int64     `json:"size"`
+	ItemType     string    `json:"item_type"`
+	ProjectType  string    `json:"project_type"`
+	TrashedAt    time.Time `json:"trashed_at"`
+	// Partial is true when the item was copied to the trash from another
+	// filesystem but could not be removed entirely from where it was. The
+	// trash holds the complete copy; what is left behind is restored over.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Trash moves cache items into a quarantine directory instead of deleting
+// them. The directory uses the freedesktop.org Trash layout (files/ and
+// info/*.trashinfo) so desktop file managers can see and restore items too.
+// The items moved there by this tool are recorded in a manifest kept
+// outside the trash, which other programs share.
+type Trash struct {
+	dir      string
+	manifest string
+	mu       sync.Mutex
+}
+
+// NewTrash creates a Trash rooted at dir that records its items in the
+// manifest file
+func NewTrash(dir, manifest string) *Trash {
+	return &Trash{dir: dir, manifest: manifest}
+}
+
+// DefaultTrashManifest returns the manifest for the trash at dir under
+// ~/.cache-remover, named after dir so that each trash has its own
+func DefaultTrashManifest(dir string) string {
+	home, _ := os.UserHomeDir()
+	abs, _ := filepath.Abs(dir)
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(home, ".cache-remover", "trash", "manifest-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// DefaultTrashDir returns the user's freedesktop.org home trash on Linux
+// and a private quarantine directory elsewhere
+func DefaultTrashDir() string {
+	home, _ := os.UserHomeDir()
+	if runtime.GOOS == "linux" {
+		if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+			return filepath.Join(dataHome, "Trash")
+		}
+		return filepath.Join(home, ".local", "share", "Trash")
+	}
+	return filepath.Join(home, ".cache-remover", "trash")
+}
+
+// Dir returns the trash directory
+func (t *Trash) Dir() string {
+	return t.dir
+}
+
+func (t *Trash) filesDir() string { return filepath.Join(t.dir, "files") }
+func (t *Trash) infoDir() string  { return filepath.Join(t.dir, "info") }
+
+// Move moves item into the trash and records it in the manifest. When the
+// item was copied but could not be removed entirely, it is recorded as a
+// partial entry and returned with a *PartialMoveError.
+func (t *Trash) Move(item CacheItem, projectType string) (*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	absPath, err := filepath.Abs(item.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(absPath); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(t.filesDir(), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.infoDir(), 0700); err != nil {
+		return nil, err
+	}
+
+	entry := TrashEntry{
+		OriginalPath: absPath,
+		Size:         item.Size,
+		ItemType:     item.Type,
+		ProjectType:  projectType,
+		TrashedAt:    time.Now(),
+	}
+	id, err := t.createTrashInfo(filepath.Base(absPath), entry)
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = id
+	moveErr := movePath(absPath, filepath.Join(t.filesDir(), id))
+	var partial *PartialMoveError
+	if moveErr != nil && !errors.As(moveErr, &partial) {
+		os.Remove(t.infoPath(id))
+		return nil, moveErr
+	}
+	// Part of the item is gone from its place, so the copy must stay
+	// restorable
+	entry.Partial = partial != nil
+
+	entries, err := t.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, entry)
+	if err := t.saveManifest(entries); err != nil {
+		return nil, err
+	}
+
+	return &entry, moveErr
+}
+
+// Entries returns the items currently recorded in the trash, oldest first
+func (t *Trash) Entries() ([]TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loadManifest()
+}
+
+// Restore moves a trashed item back to its original location. ref may be
+// the entry ID or the original path.
+func (t *Trash) Restore(ref string) (*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	index := findTrashEntry(entries, ref)
+	if index < 0 {
+		return nil, fmt.Errorf("no trashed item matches %q", ref)
+	}
+	entry := entries[index]
+
+	if entry.Partial {
+		// What was left behind is also in the copy
+		if err := RemoveAll(entry.OriginalPath).Err(); err != nil {
+			return nil, fmt.Errorf("cannot restore %s: %v", entry.OriginalPath, err)
+		}
+	}
+	if _, err := os.Lstat(entry.OriginalPath); err == nil {
+		return nil, fmt.Errorf("cannot restore %s: path already exists", entry.OriginalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.OriginalPath), 0755); err != nil {
+		return nil, err
+	}
+	if err := movePath(filepath.Join(t.filesDir(), entry.ID), entry.OriginalPath); err != nil {
+		return nil, fmt.Errorf("cannot restore %s: %v", entry.OriginalPath, err)
+	}
+	os.Remove(t.infoPath(entry.ID))
+
+	entries = append(entries[:index], entries[index+1:]...)
+	if err := t.saveManifest(entries); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Purge permanently deletes every trashed item moved there before cutoff
+func (t *Trash) Purge(cutoff time.Time) ([]TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged, kept []TrashEntry
+	var errs []error
+	for _, entry := range entries {
+		if !entry.TrashedAt.Before(cutoff) {
+			kept = append(kept, entry)
+			continue
+		}
+		if err := RemoveAll(filepath.Join(t.filesDir(), entry.ID)).Err(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", entry.OriginalPath, err))
+			kept = append(kept, entry)
+			continue
+		}
+		os.Remove(t.infoPath(entry.ID))
+		purged = append(purged, entry)
+	}
+
+	if err := t.saveManifest(kept); err != nil {
+		return purged, err
+	}
+	return purged, errors.Join(errs...)
+}
+
+func findTrashEntry(entries []TrashEntry, ref string) int {
+	absRef, _ := filepath.Abs(ref)
+	// Prefer the most recently trashed item when a path was trashed more than once
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == ref || entries[i].OriginalPath == absRef {
+			return i
+		}
+	}
+	return -1
+}
+
+// createTrashInfo reserves a trash name for entry by creating its
+// .trashinfo file exclusively, as the freedesktop.org specification
+// requires, so that programs trashing at the same time never pick the same
+// name. It returns the name.
+func (t *Trash) createTrashInfo(base string, entry TrashEntry) (string, error) {
+	name := base
+	for i := 2; ; i++ {
+		if i > 10000 {
+			return "", fmt.Errorf("cannot find a free trash name for %s", base)
+		}
+		f, err := os.OpenFile(t.infoPath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			name = base + "." + strconv.Itoa(i)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// A file without its .trashinfo, left by a crash, keeps its name
+		if _, err := os.Lstat(filepath.Join(t.filesDir(), name)); !os.IsNotExist(err) {
+			f.Close()
+			os.Remove(t.infoPath(name))
+			name = base + "." + strconv.Itoa(i)
+			continue
+		}
+
+		_, err = f.WriteString(trashInfo(entry))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(t.infoPath(name))
+			return "", err
+		}
+		return name, nil
+	}
+}
+
+func (t *Trash) infoPath(id string) string {
+	return filepath.Join(t.infoDir(), id+".trashinfo")
+}
+
+// trashInfo returns the freedesktop.org .trashinfo contents for entry
+func trashInfo(entry TrashEntry) string {
+	escaped := (&url.URL{Path: entry.OriginalPath}).EscapedPath()
+	return fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		escaped, entry.TrashedAt.Format("2006-01-02T15:04:05"))
+}
+
+func (t *Trash) loadManifest() ([]TrashEntry, error) {
+	data, err := os.ReadFile(t.manifest)
+	if os.IsNotExist(err) {
+		// Earlier versions kept the manifest inside the trash; the next
+		// save moves it
+		data, err = os.ReadFile(filepath.Join(t.dir, manifestName))
+	}
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TrashEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid trash manifest: %v", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].TrashedAt.Before(entries[j].TrashedAt)
+	})
+	return entries, nil
+}
+
+func (t *Trash) saveManifest(entries []TrashEntry) error {
+	if entries == nil {
+		entries = []TrashEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.manifest), 0700); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(t.manifest, data, 0600); err != nil {
+		return err
+	}
+	os.Remove(filepath.Join(t.dir, manifestName))
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// PartialMoveError reports an item copied to another filesystem whose
+// original could only be removed in part. The copy at Dst is complete.
+type PartialMoveError struct {
+	Src, Dst string
+	Err      error
+}
+
+func (e *PartialMoveError) Error() string {
+	return fmt.Sprintf("copied %s to %s but could not remove all of the original: %v", e.Src, e.Dst, e.Err)
+}
+
+func (e *PartialMoveError) Unwrap() error {
+	return e.Err
+}
+
+// rename and removeTree are replaced by tests to move across filesystems
+var (
+	rename     = os.Rename
+	removeTree = RemoveAll
+)
+
+// movePath renames src to dst, falling back to copy-and-delete when they
+// are on different filesystems. Once the copy is complete, dst holds the
+// only full copy of whatever was removed from src, so it is kept even if
+// src cannot be removed entirely; the error is then a *PartialMoveError.
+func movePath(src, dst string) error {
+	err := rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		removeTree(dst)
+		return err
+	}
+	if err := removeTree(src).Err(); err != nil {
+		return &PartialMoveError{Src: src, Dst: dst, Err: err}
+	}
+	return nil
+}
+
+// copyTree copies files, directories and symlinks from src to dst
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
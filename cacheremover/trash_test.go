@@ -0,0 +1,209 @@
+package cacheremover
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestTrash creates a trash in dir with its manifest beside it
+func newTestTrash(dir string) *Trash {
+	return NewTrash(filepath.Join(dir, "trash"), filepath.Join(dir, "manifest.json"))
+}
+
+func TestTrashMoveAndRestore(t *testing.T) {
+	tempDir := t.TempDir()
+	trash := newTestTrash(tempDir)
+
+	cacheDir := filepath.Join(tempDir, "app", "build")
+	os.MkdirAll(cacheDir, 0755)
+	os.WriteFile(filepath.Join(cacheDir, "asset.css"), []byte("body {}"), 0644)
+
+	entry, err := trash.Move(CacheItem{Path: cacheDir, Size: 7, Type: "directory"}, "Node.js")
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Error("Cache directory should be gone after moving to trash")
+	}
+	if entry.ProjectType != "Node.js" || entry.Size != 7 || entry.OriginalPath != cacheDir {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	info, err := os.ReadFile(filepath.Join(trash.Dir(), "info", entry.ID+".trashinfo"))
+	if err != nil {
+		t.Fatalf("Missing .trashinfo file: %v", err)
+	}
+	if !strings.Contains(string(info), "Path="+cacheDir) {
+		t.Errorf("Unexpected .trashinfo contents: %s", info)
+	}
+
+	entries, err := trash.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected 1 manifest entry, got %d (%v)", len(entries), err)
+	}
+
+	if _, err := trash.Restore(cacheDir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(cacheDir, "asset.css")); err != nil || string(data) != "body {}" {
+		t.Errorf("Restored file missing or changed: %q (%v)", data, err)
+	}
+
+	entries, _ = trash.Entries()
+	if len(entries) != 0 {
+		t.Errorf("Expected empty manifest after restore, got %d entries", len(entries))
+	}
+}
+
+func TestTrashNameCollision(t *testing.T) {
+	tempDir := t.TempDir()
+	trash := newTestTrash(tempDir)
+
+	// Another program has reserved the name but not moved its file yet
+	writeFiles(t, trash.Dir(), map[string]string{"info/node_modules.trashinfo": "[Trash Info]\n"})
+
+	var ids []string
+	for _, project := range []string{"one", "two"} {
+		cacheDir := filepath.Join(tempDir, project, "node_modules")
+		os.MkdirAll(cacheDir, 0755)
+		entry, err := trash.Move(CacheItem{Path: cacheDir, Type: "directory"}, "Node.js")
+		if err != nil {
+			t.Fatalf("Move failed: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	if strings.Join(ids, ",") != "node_modules.2,node_modules.3" {
+		t.Errorf("Expected the reserved name to be left alone, got %v", ids)
+	}
+}
+
+func TestTrashManifestOutsideTrash(t *testing.T) {
+	tempDir := t.TempDir()
+	trash := newTestTrash(tempDir)
+
+	// A manifest left inside the trash by an earlier version is moved out
+	legacy := filepath.Join(trash.Dir(), manifestName)
+	writeFiles(t, trash.Dir(), map[string]string{manifestName: `[{"id": "old", "original_path": "/old"}]`})
+
+	filePath := filepath.Join(tempDir, "module.pyc")
+	os.WriteFile(filePath, []byte("bytecode"), 0644)
+	if _, err := trash.Move(CacheItem{Path: filePath, Size: 8, Type: "file"}, "Python"); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+
+	entries, err := trash.Entries()
+	if err != nil || len(entries) != 2 || entries[0].ID != "old" {
+		t.Errorf("Expected the earlier entry kept, got %+v (%v)", entries, err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "manifest.json")); err != nil {
+		t.Errorf("Expected the manifest outside the trash: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("Expected no manifest left in the trash, got %v", err)
+	}
+}
+
+func TestTrashPurge(t *testing.T) {
+	tempDir := t.TempDir()
+	trash := newTestTrash(tempDir)
+
+	filePath := filepath.Join(tempDir, "module.pyc")
+	os.WriteFile(filePath, []byte("bytecode"), 0644)
+	entry, err := trash.Move(CacheItem{Path: filePath, Size: 8, Type: "file"}, "Python")
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+
+	// Items newer than the cutoff are kept
+	purged, err := trash.Purge(time.Now().Add(-time.Hour))
+	if err != nil || len(purged) != 0 {
+		t.Fatalf("Expected nothing purged, got %d (%v)", len(purged), err)
+	}
+
+	purged, err = trash.Purge(time.Now().Add(time.Second))
+	if err != nil || len(purged) != 1 {
+		t.Fatalf("Expected 1 item purged, got %d (%v)", len(purged), err)
+	}
+	if _, err := os.Lstat(filepath.Join(trash.Dir(), "files", entry.ID)); !os.IsNotExist(err) {
+		t.Error("Purged item should be deleted from the trash")
+	}
+}
+
+func TestCleanerUsesTrash(t *testing.T) {
+	tempDir := t.TempDir()
+	cleaner := newTestCleaner()
+	cleaner.Trash = newTestTrash(tempDir)
+
+	cacheDir := filepath.Join(tempDir, "app", "target")
+	os.MkdirAll(cacheDir, 0755)
+	project := &Project{
+		Path:  filepath.Dir(cacheDir),
+		Type:  &ProjectType{Name: "Rust"},
+		Items: []CacheItem{{Path: cacheDir, Size: 42, Type: "directory"}},
+	}
+
+	result, err := cleaner.CleanProject(context.Background(), project)
+	if err != nil {
+		t.Fatalf("CleanProject failed: %v", err)
+	}
+	if !result.Trashed || len(result.Removed) != 1 {
+		t.Errorf("Expected 1 trashed item, got %+v", result)
+	}
+
+	entries, _ := cleaner.Trash.Entries()
+	if len(entries) != 1 || entries[0].ProjectType != "Rust" {
+		t.Errorf("Expected Rust entry in manifest, got %+v", entries)
+	}
+}
+
+func TestTrashPartialMoveKeepsCopy(t *testing.T) {
+	tempDir := t.TempDir()
+	trash := newTestTrash(tempDir)
+	cacheDir := filepath.Join(tempDir, "app", "build")
+	writeFiles(t, cacheDir, map[string]string{"a.css": "a {}", "b.css": "b {}"})
+
+	// Move across filesystems, and fail to remove b.css after a.css is gone
+	rename = func(src, dst string) error {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: syscall.EXDEV}
+	}
+	removeTree = func(path string) *RemoveReport {
+		if path != cacheDir {
+			return RemoveAll(path)
+		}
+		os.Remove(filepath.Join(cacheDir, "a.css"))
+		return &RemoveReport{Failures: []*os.PathError{{Op: "unlinkat", Path: filepath.Join(cacheDir, "b.css"), Err: syscall.EPERM}}}
+	}
+	defer func() { rename, removeTree = os.Rename, RemoveAll }()
+
+	entry, err := trash.Move(CacheItem{Path: cacheDir, Size: 8, Type: "directory"}, "Node.js")
+	var partial *PartialMoveError
+	if !errors.As(err, &partial) || entry == nil || !entry.Partial {
+		t.Fatalf("Expected a partial move, got %+v (%v)", entry, err)
+	}
+	for _, name := range []string{"a.css", "b.css"} {
+		if _, err := os.Stat(filepath.Join(trash.Dir(), "files", entry.ID, name)); err != nil {
+			t.Errorf("Expected %s in the trash: %v", name, err)
+		}
+	}
+	if entries, _ := trash.Entries(); len(entries) != 1 || !entries[0].Partial {
+		t.Fatalf("Expected a partial manifest entry, got %+v", entries)
+	}
+
+	// Restoring replaces what was left behind with the complete copy
+	rename, removeTree = os.Rename, RemoveAll
+	if _, err := trash.Restore(entry.ID); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	for name, content := range map[string]string{"a.css": "a {}", "b.css": "b {}"} {
+		if data, err := os.ReadFile(filepath.Join(cacheDir, name)); err != nil || string(data) != content {
+			t.Errorf("Expected %s restored, got %q (%v)", name, data, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cacheremover
+
+import "os"
+
+// fileID identifies a file independently of the names linked to it
+type fileID struct {
+	dev, ino uint64
+}
+
+// sizeCounter adds up the sizes of a set of files the way du does. Each
+// hard-linked file counts once, and only files whose links all belong to
+// the set count as reclaimable: deleting a pnpm node_modules whose files
+// are linked from the shared store frees next to nothing.
+type sizeCounter struct {
+	reclaimable int64
+	apparent    int64
+	linked      map[fileID]*linkedFile
+}
+
+// linkedFile is a file with several hard links
+type linkedFile struct {
+	allocated int64
+	size      int64
+	links     uint64
+	seen      uint64
+}
+
+// add counts a file or directory. Directories only add the space they
+// occupy on disk.
+func (c *sizeCounter) add(info os.FileInfo) {
+	id, allocated, links, ok := fileUsage(info)
+	if !info.IsDir() {
+		if f := c.linked[id]; ok && f != nil {
+			f.seen++
+			return
+		}
+		c.apparent += info.Size()
+	}
+	if !ok || links <= 1 || info.IsDir() {
+		c.reclaimable += allocated
+		return
+	}
+	if c.linked == nil {
+		c.linked = make(map[fileID]*linkedFile)
+	}
+	c.linked[id] = &linkedFile{allocated: allocated, size: info.Size(), links: links, seen: 1}
+}
+
+// merge adds the files counted by other, which may include links to the
+// same files
+func (c *sizeCounter) merge(other *sizeCounter) {
+	c.reclaimable += other.reclaimable
+	c.apparent += other.apparent
+	for id, f := range other.linked {
+		if mine := c.linked[id]; mine != nil {
+			mine.seen += f.seen
+			c.apparent -= f.size
+			continue
+		}
+		if c.linked == nil {
+			c.linked = make(map[fileID]*linkedFile)
+		}
+		c.linked[id] = f
+	}
+}
+
+// sizes returns the bytes freed by deleting every file counted, and their
+// apparent size
+func (c *sizeCounter) sizes() (reclaimable, apparent int64) {
+	reclaimable = c.reclaimable
+	for _, f := range c.linked {
+		if f.seen >= f.links {
+			reclaimable += f.allocated
+		}
+	}
+	return reclaimable, c.apparent
+}
+
+// fileSizes returns the reclaimable and apparent size of a single file
+func fileSizes(info os.FileInfo) (reclaimable, apparent int64) {
+	var c sizeCounter
+	c.add(info)
+	return c.sizes()
+}
@@ -0,0 +1,81 @@
+package cacheremover
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeRandom writes size bytes that no filesystem can compress away
+func writeRandom(t *testing.T, path string, size int) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.Read(data)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirSizeHardLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard links are not detected on Windows")
+	}
+	root := t.TempDir()
+	store := filepath.Join(root, "store")
+	modules := filepath.Join(root, "node_modules")
+	writeRandom(t, filepath.Join(store, "react.js"), 64*1024)
+	os.MkdirAll(modules, 0755)
+	if err := os.Link(filepath.Join(store, "react.js"), filepath.Join(modules, "react.js")); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+	// A second link inside the same tree does not count twice
+	os.Link(filepath.Join(store, "react.js"), filepath.Join(modules, "react-copy.js"))
+
+	// The store keeps the data alive, so removing node_modules frees
+	// nothing beyond its directory
+	size, apparent := dirSize(modules)
+	if apparent != 64*1024 {
+		t.Errorf("Expected the linked file to count once, got %d apparent bytes", apparent)
+	}
+	if size >= 64*1024 {
+		t.Errorf("Expected data shared with the store not to be reclaimable, got %d", size)
+	}
+
+	// With all links inside, the data counts once
+	size, apparent = dirSize(root)
+	if apparent != 64*1024 || size < 64*1024 || size >= 2*64*1024 {
+		t.Errorf("Expected the file to count once, got %d bytes (%d apparent)", size, apparent)
+	}
+
+	report := RemoveAll(modules)
+	if report.Err() != nil || report.BytesFreed >= 64*1024 {
+		t.Errorf("Removing the links should free no file data, got %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(store, "react.js")); err != nil {
+		t.Errorf("The store should keep its file: %v", err)
+	}
+}
+
+func TestDirSizeSparseFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("allocated blocks are not available on Windows")
+	}
+	root := t.TempDir()
+	f, err := os.Create(filepath.Join(root, "disk.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Truncate(100 << 20)
+	f.Close()
+
+	size, apparent := dirSize(root)
+	if apparent != 100<<20 {
+		t.Errorf("Expected 100 MB apparent size, got %d", apparent)
+	}
+	if size >= 1<<20 {
+		t.Errorf("A sparse file should occupy next to no disk space, got %d", size)
+	}
+}
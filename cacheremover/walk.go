@@ -0,0 +1,711 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// walkParallelism is how many directories a walk reads concurrently.
+// Reading directories waits on the disk far more than on the CPU.
+const walkParallelism = 16
+
+// scanWalk is one traversal of a directory tree. Every directory is read
+// once with os.ReadDir, and while it is read the walk discovers projects,
+// matches the cache items of every project it lies in, adds up the sizes
+// of cache directories and records activity. Subdirectories are handed to
+// idle goroutines as they are found, up to walkParallelism, and walked
+// inline otherwise, so no goroutine waits while there is work.
+type scanWalk struct {
+	s   *Scanner
+	ctx context.Context
+	sem chan struct{}
+
+	// discover looks for projects below the root; collect scans them
+	discover bool
+	collect  bool
+
+	// mounts holds the mount points below the root, which includes bind
+	// mounts that the device number does not reveal
+	mounts map[string]bool
+
+	mu sync.Mutex
+	// members holds the workspace members the walk has not reached yet.
+	// Without collect the values are nil.
+	members map[string]*projectScan
+}
+
+func (s *Scanner) newWalk(ctx context.Context, discover, collect bool) *scanWalk {
+	return &scanWalk{
+		s:        s,
+		ctx:      ctx,
+		sem:      make(chan struct{}, walkParallelism),
+		discover: discover,
+		collect:  collect,
+		members:  make(map[string]*projectScan),
+	}
+}
+
+// projectScan collects the cache items and activity of one project while
+// the walk passes through it
+type projectScan struct {
+	path    string
+	project *Project // Set once the walk reaches the project directory
+
+	config     CacheConfig
+	dirs       []*pattern
+	signatures [][]*pattern // By index in dirs
+	files      []*pattern
+
+	// root is the workspace root of a member, members those of a root
+	root    *projectScan
+	members []*projectScan
+	// skip holds the members of the workspace, which collect their own items
+	skip map[string]bool
+
+	activity bool // Record LastActivity
+	mu       sync.Mutex
+	latest   time.Time
+}
+
+func newProjectScan(path string, config CacheConfig) *projectScan {
+	ps := &projectScan{path: path}
+	ps.configure(config)
+	return ps
+}
+
+// configure compiles the patterns of the cache configuration
+func (ps *projectScan) configure(config CacheConfig) {
+	ps.config = config
+	ps.dirs = compilePatterns(config.Directories)
+	ps.signatures = make([][]*pattern, len(ps.dirs))
+	for i, dir := range ps.dirs {
+		ps.signatures[i] = compilePatterns(config.Signatures[dir.raw])
+	}
+	ps.files = compilePatterns(config.Files)
+}
+
+func (ps *projectScan) touch(modTime time.Time) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if modTime.After(ps.latest) {
+		ps.latest = modTime
+	}
+}
+
+// scanState is what a project collects in one directory
+type scanState struct {
+	scan     *projectScan
+	items    bool // Look for cache items
+	activity bool // Record file modification times
+}
+
+// dirTask is a directory to visit and what to do there
+type dirTask struct {
+	path  string
+	info  os.FileInfo
+	fs    filesystem
+	depth int
+
+	discover bool // Look for projects here and below
+	size     bool // The directory is inside a cache directory being sized
+	scans    []scanState
+	// nested is set in the walk of a single project for directories that
+	// discovery would look into, where a nested project takes over the items
+	nested bool
+	// start lists the projects that begin at this directory
+	start []*projectScan
+	// matches lists the projects for which the directory is a cache item
+	matches []dirMatch
+}
+
+// dirMatch is a directory that matched a cache directory pattern of a
+// project, or carries a CACHEDIR.TAG
+type dirMatch struct {
+	scan *projectScan
+	rank int
+}
+
+// foundItem is a cache item found for a project. Items are reported
+// ordered by rank, and in walk order within a rank: tagged directories
+// first, then by the pattern that matched them, then files by extension.
+type foundItem struct {
+	scan *projectScan
+	item CacheItem
+	rank int
+}
+
+// dirResult is what a walk found in a directory and below it
+type dirResult struct {
+	usage    *sizeCounter // Only for directories being sized
+	found    []foundItem
+	projects []*projectScan // Projects discovered, in walk order
+}
+
+// walkPart is the result of one entry of a directory. Subdirectories
+// carry their task, whose matches become items once their size is known.
+type walkPart struct {
+	task   *dirTask
+	result dirResult
+}
+
+// rootTask prepares the walk of root, following a symlink at root itself
+func (w *scanWalk) rootTask(root string) (dirTask, bool) {
+	info, err := os.Stat(root)
+	if err != nil || !info.IsDir() {
+		if err != nil && w.discover {
+			w.s.emit(EventAccessError, root, err)
+		}
+		return dirTask{}, false
+	}
+	w.mounts = mountPointsIn(root)
+	return dirTask{path: root, info: info, fs: filesystemFor(info), discover: w.discover}, true
+}
+
+// run walks from a root task and returns what it found
+func (w *scanWalk) run(task dirTask) (dirResult, error) {
+	result := w.visit(task)
+	return result, w.ctx.Err()
+}
+
+// walkProject walks the directory of a single project, collecting its
+// cache items or only its activity
+func (w *scanWalk) walkProject(ps *projectScan, items bool) error {
+	task, ok := w.rootTask(ps.path)
+	if !ok {
+		w.finish(ps, nil)
+		return nil
+	}
+	task.scans = []scanState{{scan: ps, items: items, activity: ps.activity}}
+	task.start = []*projectScan{ps}
+	if items {
+		w.s.Index.walk(ps.path)
+	}
+	_, err := w.run(task)
+	return err
+}
+
+// visit walks one directory and everything below it that the task needs
+func (w *scanWalk) visit(t dirTask) dirResult {
+	var r dirResult
+	if w.ctx.Err() != nil {
+		return r
+	}
+	if t.size && !t.discover && len(t.scans) == 0 {
+		return w.visitSized(t)
+	}
+	if t.size {
+		r.usage = &sizeCounter{}
+		r.usage.add(t.info)
+	}
+
+	entries, err := os.ReadDir(t.path)
+	if err != nil && (t.discover || len(t.scans) > 0) {
+		// Entries read before the error are still walked
+		w.s.emit(EventAccessError, t.path, err)
+	}
+	w.beginProjects(&t, &r, entries)
+
+	var parts []*walkPart
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		path := filepath.Join(t.path, entry.Name())
+		if !entry.IsDir() {
+			if part := w.visitFile(&t, r.usage, entry, path); part != nil {
+				parts = append(parts, part)
+			}
+			continue
+		}
+
+		child, ok := w.childTask(&t, entry, path)
+		if !ok {
+			continue
+		}
+		part := &walkPart{task: &child}
+		parts = append(parts, part)
+		select {
+		case w.sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-w.sem }()
+				part.result = w.visit(*part.task)
+			}()
+		default:
+			part.result = w.visit(*part.task)
+		}
+	}
+	wg.Wait()
+
+	for _, part := range parts {
+		if part.task != nil {
+			w.matchedDir(part)
+		}
+		r.found = append(r.found, part.result.found...)
+		r.projects = append(r.projects, part.result.projects...)
+		if r.usage != nil && part.result.usage != nil {
+			r.usage.merge(part.result.usage)
+		}
+	}
+
+	for _, ps := range t.start {
+		r.found = w.finish(ps, r.found)
+		// A cancelled walk leaves projects incomplete
+		if w.discover && ps.root == nil && w.ctx.Err() == nil {
+			w.s.emitEvent(Event{Kind: EventProjectScanned, Path: ps.path, Project: ps.project})
+		}
+	}
+	return r
+}
+
+// visitSized adds up the size of a directory where nothing else is looked
+// for, such as the inside of a node_modules. A directory the index shows
+// unchanged is not read: its files are counted from the index and only its
+// subdirectories are examined.
+func (w *scanWalk) visitSized(t dirTask) dirResult {
+	r := dirResult{usage: &sizeCounter{}}
+	r.usage.add(t.info)
+
+	var children []dirTask
+	child := func(name string, info os.FileInfo) {
+		// Mount points inside a cache directory are not part of its size
+		path := filepath.Join(t.path, name)
+		if t.fs.contains(info) && !w.mounts[path] {
+			children = append(children, dirTask{path: path, info: info, fs: t.fs, depth: t.depth + 1, size: true})
+		}
+	}
+
+	if dir, ok := w.s.Index.lookup(t.path, t.info); ok {
+		r.usage.merge(dir.files())
+		for _, name := range dir.Dirs {
+			if info, err := os.Lstat(filepath.Join(t.path, name)); err == nil && info.IsDir() {
+				child(name, info)
+			}
+		}
+	} else {
+		entries, err := os.ReadDir(t.path)
+		files := &sizeCounter{}
+		var dirs []string
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			if entry.IsDir() {
+				dirs = append(dirs, entry.Name())
+				child(entry.Name(), info)
+			} else {
+				files.add(info)
+			}
+		}
+		if err == nil {
+			w.s.Index.store(t.path, t.info, newIndexedDir(files, dirs))
+		}
+		r.usage.merge(files)
+	}
+
+	results := make([]dirResult, len(children))
+	var wg sync.WaitGroup
+	for i := range children {
+		i := i
+		select {
+		case w.sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-w.sem }()
+				results[i] = w.visit(children[i])
+			}()
+		default:
+			results[i] = w.visit(children[i])
+		}
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.usage != nil {
+			r.usage.merge(result.usage)
+		}
+	}
+	return r
+}
+
+// beginProjects starts scanning the workspace member or the newly
+// discovered project at the task's directory
+func (w *scanWalk) beginProjects(t *dirTask, r *dirResult, entries []os.DirEntry) {
+	if member, ok := w.takeMember(t.path); ok {
+		if member != nil {
+			w.begin(member, entries)
+			t.scans = append(t.scans, scanState{scan: member, items: true})
+			t.start = append(t.start, member)
+		}
+		return
+	}
+	if !t.discover {
+		if t.nested && len(w.s.detectProjectTypes(t.path, entries)) > 0 {
+			// Scanned on its own; the enclosing projects leave it its items
+			t.scans = withoutItems(t.scans)
+		}
+		return
+	}
+
+	var ps *projectScan
+	switch {
+	case w.collect:
+		ps = w.newProject(t.path, entries)
+	case len(w.s.detectProjectTypes(t.path, entries)) > 0:
+		ps = &projectScan{path: t.path}
+		w.addMembers(ps)
+	}
+	if ps == nil {
+		return
+	}
+	w.s.emit(EventProjectFound, t.path, nil)
+	if w.collect {
+		ps.activity = w.s.MeasureActivity
+		// Each cache item belongs to the innermost project only
+		t.scans = append(withoutItems(t.scans), scanState{scan: ps, items: true, activity: ps.activity})
+		t.start = append(t.start, ps)
+	}
+	r.projects = append(r.projects, ps)
+}
+
+// withoutItems stops the projects of scans from collecting cache items
+// below a nested project, while still recording their activity
+func withoutItems(scans []scanState) []scanState {
+	next := make([]scanState, 0, len(scans))
+	for _, state := range scans {
+		state.items = false
+		if state.activity {
+			next = append(next, state)
+		}
+	}
+	return next
+}
+
+// newProject creates the scan of a project and of its workspace members.
+// It returns nil when path, whose entries are given, is not a project.
+func (w *scanWalk) newProject(path string, entries []os.DirEntry) *projectScan {
+	types := w.s.detectProjectTypes(path, entries)
+	if len(types) == 0 {
+		return nil
+	}
+	projectType := CombineProjectTypes(types)
+	ps := newProjectScan(path, projectType.CacheConfig)
+	ps.project = &Project{Path: path, Type: projectType, Types: types}
+	w.addMembers(ps)
+	return ps
+}
+
+// addMembers registers the workspace members of a project, which the walk
+// scans as projects of their own when it reaches them
+func (w *scanWalk) addMembers(ps *projectScan) {
+	memberPaths := w.s.workspaceMembers(ps.path)
+	if len(memberPaths) == 0 {
+		return
+	}
+
+	ps.skip = make(map[string]bool, len(memberPaths))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, memberPath := range memberPaths {
+		if memberPath == ps.path {
+			continue
+		}
+		ps.skip[memberPath] = true
+		if !w.collect {
+			w.members[memberPath] = nil
+			continue
+		}
+		member := &projectScan{path: memberPath, root: ps, skip: ps.skip}
+		ps.members = append(ps.members, member)
+		w.members[memberPath] = member
+	}
+}
+
+func (w *scanWalk) takeMember(path string) (*projectScan, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	member, ok := w.members[path]
+	if ok {
+		delete(w.members, path)
+	}
+	return member, ok
+}
+
+func (w *scanWalk) isMember(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.members[path]
+	return ok
+}
+
+// begin detects the type of a workspace member, falling back to the type
+// of its workspace root
+func (w *scanWalk) begin(member *projectScan, entries []os.DirEntry) {
+	types := w.s.detectProjectTypes(member.path, entries)
+	projectType := CombineProjectTypes(types)
+	if projectType == nil {
+		projectType = member.root.project.Type
+		types = []*ProjectType{projectType}
+	}
+	member.configure(projectType.CacheConfig)
+	member.project = &Project{Path: member.path, Type: projectType, Types: types}
+}
+
+// childTask decides what the walk does in a subdirectory. It reports
+// false when nothing needs the subdirectory.
+func (w *scanWalk) childTask(t *dirTask, entry os.DirEntry, path string) (dirTask, bool) {
+	info, err := entry.Info()
+	if err != nil {
+		// Removed since the directory was read
+		return dirTask{}, false
+	}
+	s := w.s
+	name := entry.Name()
+	child := dirTask{path: path, info: info, fs: t.fs, depth: t.depth + 1}
+	mount := !t.fs.contains(info) || w.mounts[path]
+
+	tagChecked, tagged := false, false
+	isTagged := func() bool {
+		if !tagChecked {
+			tagChecked, tagged = true, HasCacheDirTag(path)
+		}
+		return tagged
+	}
+
+	mountReported := false
+	if t.discover {
+		switch {
+		case child.depth > s.MaxDepth:
+		case s.SkipHidden && strings.HasPrefix(name, "."):
+		case mount && s.OneFileSystem:
+			s.emit(EventMountPointSkipped, path, nil)
+			mountReported = true
+		case s.IsCacheDirectory(name) || isTagged():
+			// Cache directories are removed as units; no project is inside
+			s.emit(EventCacheDirSkipped, path, nil)
+		case s.isExcludedDir(path):
+			s.emit(EventExcluded, path, nil)
+		default:
+			child.discover = true
+			if mount {
+				child.fs = filesystemFor(info)
+			}
+		}
+	} else if len(t.scans) > 0 && !mount {
+		hidden := s.SkipHidden && strings.HasPrefix(name, ".")
+		child.nested = !hidden && !s.IsCacheDirectory(name) && !isTagged() && !s.isExcludedDir(path)
+	}
+
+	// Mount points inside a project are never scanned, sized or cleaned
+	if mount {
+		if len(t.scans) > 0 && !mountReported {
+			s.emit(EventMountPointSkipped, path, nil)
+		}
+		return child, child.discover
+	}
+
+	for _, state := range t.scans {
+		ps := state.scan
+		next := scanState{scan: ps}
+		if state.items && !ps.skip[path] {
+			if rank, ok := w.matchDir(ps, path, isTagged); ok {
+				child.matches = append(child.matches, dirMatch{scan: ps, rank: rank})
+			} else {
+				next.items = true
+			}
+		}
+		if state.activity && name != ".git" && !s.IsCacheDirectory(name) && !matchesAnyDir(ps.dirs, ps.path, path) && !isTagged() {
+			next.activity = true
+		}
+		if next.items || next.activity {
+			child.scans = append(child.scans, next)
+		}
+	}
+	child.size = t.size || len(child.matches) > 0
+
+	needed := child.discover || child.size || len(child.scans) > 0 || w.isMember(path)
+	return child, needed
+}
+
+// matchDir reports whether a directory is a cache directory of a project
+// and the rank of its item
+func (w *scanWalk) matchDir(ps *projectScan, path string, isTagged func() bool) (int, bool) {
+	if isTagged() {
+		return 0, true
+	}
+	mismatch := false
+	for i, dir := range ps.dirs {
+		if !dir.matchDir(ps.path, path) {
+			continue
+		}
+		if hasSignature(path, ps.signatures[i]) {
+			return 1 + i, true
+		}
+		mismatch = true
+	}
+	if mismatch {
+		// A source folder that happens to have a cache name
+		w.s.emit(EventSignatureMismatch, path, nil)
+	}
+	return 0, false
+}
+
+// matchedDir turns the matches of a walked subdirectory into cache items.
+// An empty directory is no item; its subdirectories are searched instead.
+func (w *scanWalk) matchedDir(part *walkPart) {
+	if len(part.task.matches) == 0 || part.result.usage == nil {
+		// Not matched, or the walk was cancelled
+		return
+	}
+	size, apparent := part.result.usage.sizes()
+
+	var found []foundItem
+	for _, match := range part.task.matches {
+		if apparent > 0 {
+			found = append(found, foundItem{
+				scan: match.scan,
+				item: CacheItem{Path: part.task.path, Size: size, ApparentSize: apparent, Type: "directory"},
+				rank: match.rank,
+			})
+			continue
+		}
+		rescan := w.visit(dirTask{
+			path:  part.task.path,
+			info:  part.task.info,
+			fs:    part.task.fs,
+			scans: []scanState{{scan: match.scan, items: true}},
+		})
+		found = append(found, rescan.found...)
+	}
+	part.result.found = append(found, part.result.found...)
+}
+
+// visitFile sizes a file inside a cache directory, records its
+// modification time and matches it against the cache file patterns of
+// the projects it belongs to
+func (w *scanWalk) visitFile(t *dirTask, usage *sizeCounter, entry os.DirEntry, path string) *walkPart {
+	var info os.FileInfo
+	stat := func() bool {
+		if info == nil {
+			var err error
+			if info, err = entry.Info(); err != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	if usage != nil && stat() {
+		usage.add(info)
+	}
+
+	var part *walkPart
+	for _, state := range t.scans {
+		ps := state.scan
+		// Without file patterns only the extension of the name matters
+		rel := entry.Name()
+		if len(ps.files) > 0 {
+			rel = relSlash(ps.path, path)
+		}
+		if state.activity && !isCacheFile(rel, ps.files, ps.config.Extensions) && stat() {
+			ps.touch(info.ModTime())
+		}
+		if !state.items {
+			continue
+		}
+		if rank, ok := w.matchFile(ps, entry, path, rel); ok && stat() {
+			if part == nil {
+				part = &walkPart{}
+			}
+			part.result.found = append(part.result.found, foundItem{scan: ps, item: newFileItem(path, info), rank: rank})
+		}
+	}
+	return part
+}
+
+// matchFile reports whether a file is a cache item of a project and the
+// rank of its item. A symlink to a directory matches the cache directory
+// patterns, such as a node_modules linked to a shared store.
+func (w *scanWalk) matchFile(ps *projectScan, entry os.DirEntry, path, rel string) (int, bool) {
+	if entry.Type()&os.ModeSymlink != 0 {
+		for i, dir := range ps.dirs {
+			if !dir.matchDir(ps.path, path) {
+				continue
+			}
+			if target, err := os.Stat(path); err == nil && target.IsDir() && hasSignature(path, ps.signatures[i]) {
+				return 1 + i, true
+			}
+		}
+	}
+	for i, file := range ps.files {
+		if file.matchPath(rel) {
+			return 1 + len(ps.dirs) + i, true
+		}
+	}
+	for _, ext := range ps.config.Extensions {
+		if strings.HasSuffix(entry.Name(), ext) {
+			return 1 + len(ps.dirs) + len(ps.files), true
+		}
+	}
+	return 0, false
+}
+
+// finish completes the Project of a scan from the items found below its
+// directory, which it takes out of found. A workspace root adds the items
+// of its members, including members the walk never reached.
+func (w *scanWalk) finish(ps *projectScan, found []foundItem) []foundItem {
+	var own []foundItem
+	rest := found[:0]
+	for _, f := range found {
+		if f.scan == ps {
+			own = append(own, f)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	sort.SliceStable(own, func(i, j int) bool { return own[i].rank < own[j].rank })
+
+	items := make([]CacheItem, 0, len(own))
+	for _, f := range own {
+		items = append(items, f.item)
+	}
+	if len(items) > 0 {
+		items = w.s.checkExcluded(ps.path, ps.config, items)
+		items = w.s.checkGitStatus(ps.path, items)
+	}
+
+	project := ps.project
+	project.Items = items
+	for _, item := range items {
+		project.TotalSize += item.Size
+		project.ApparentSize += item.ApparentSize
+	}
+
+	for _, member := range ps.members {
+		if member.project == nil {
+			entries, _ := os.ReadDir(member.path)
+			w.begin(member, entries)
+			w.finish(member, nil)
+		}
+		project.Members = append(project.Members, member.project)
+		project.Items = append(project.Items, member.project.Items...)
+		project.TotalSize += member.project.TotalSize
+		project.ApparentSize += member.project.ApparentSize
+	}
+
+	if ps.activity {
+		project.LastActivity = ps.latest
+		if w.s.GitActivity {
+			if repo := w.s.RepoInfo(ps.path); repo != nil && repo.LastCommit.After(project.LastActivity) {
+				project.LastActivity = repo.LastCommit
+			}
+		}
+	}
+	return rest
+}
@@ -0,0 +1,173 @@
+package cacheremover
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanMatchesScanProject(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		// A workspace with two members
+		"shop/package.json":                            `{"workspaces": ["packages/*"]}`,
+		"shop/node_modules/react/package.json":         "react",
+		"shop/packages/ui/package.json":                `{}`,
+		"shop/packages/ui/node_modules/x/package.json": "x",
+		"shop/packages/api/package.json":               `{}`,
+		"shop/packages/api/dist/server.js":             "server",
+		"shop/packages/api/src/tools/setup.py":         "",
+		"shop/packages/api/src/tools/app.pyc":          "bytecode",
+		"shop/packages/api/src/tools/__init__.py":      "",
+		// A project nested in another project's sources
+		"site/package.json":                     `{}`,
+		"site/dist/index.html":                  "<html>",
+		"site/docs/requirements.txt":            "mkdocs",
+		"site/docs/__pycache__/conf.pyc":        "bytecode",
+		"site/docs/build/" + CacheDirTagName:    cacheDirTagSignature,
+		"site/docs/build/html/index.html":       "<html>",
+		"notes/todo.txt":                        "not a project",
+		"notes/node_modules/stray/package.json": `{}`,
+	})
+
+	scanner := newTestScanner()
+	projects, err := scanner.Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	var paths []string
+	for _, project := range projects {
+		paths = append(paths, relSlash(root, project.Path))
+	}
+	if want := []string{"shop", "shop/packages/api/src/tools", "site", "site/docs"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Expected projects %v in walk order, got %v", want, paths)
+	}
+
+	// The single walk finds what scanning each project on its own finds
+	for _, project := range projects {
+		alone, err := scanner.ScanProject(context.Background(), project.Path)
+		if err != nil {
+			t.Fatalf("ScanProject failed: %v", err)
+		}
+		if !reflect.DeepEqual(project.Items, alone.Items) || project.TotalSize != alone.TotalSize {
+			t.Errorf("%s: Scan found %+v, ScanProject %+v", project.Path, project.Items, alone.Items)
+		}
+		if len(project.Members) != len(alone.Members) {
+			t.Errorf("%s: Scan found %d members, ScanProject %d", project.Path, len(project.Members), len(alone.Members))
+		}
+	}
+
+	shop := projects[0]
+	if got, want := itemPaths(shop.Path, shop.Items), "node_modules,packages/api/dist,packages/ui/node_modules"; got != want {
+		t.Errorf("Expected workspace items %s, got %s", want, got)
+	}
+	if len(shop.Members) != 2 || len(shop.Members[0].Items) != 1 || len(shop.Members[1].Items) != 1 {
+		t.Errorf("Expected the items split between api and ui, got %+v", shop.Members)
+	}
+}
+
+func TestNestedProjectOwnsItsItems(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a/package.json":                            `{}`,
+		"a/node_modules/x/package.json":             "x",
+		"a/sub/b/package.json":                      `{}`,
+		"a/sub/b/node_modules/y/package.json":       "y",
+		"a/sub/b/build/" + CacheDirTagName:          cacheDirTagSignature,
+		"a/sub/b/build/out.bin":                     "out",
+		"a/sub/.hidden/package.json":                `{}`,
+		"a/sub/.hidden/node_modules/z/package.json": "z",
+	})
+
+	scanner := newTestScanner()
+	scanner.SkipHidden = true
+	projects, err := scanner.Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("Expected projects a and a/sub/b, got %+v", projects)
+	}
+
+	// Each item is listed under the innermost project that was discovered
+	want := map[string]string{
+		"a":       "node_modules,sub/.hidden/node_modules",
+		"a/sub/b": "build,node_modules",
+	}
+	for _, project := range projects {
+		rel := relSlash(root, project.Path)
+		if got := itemPaths(project.Path, project.Items); got != want[rel] {
+			t.Errorf("%s: expected items %s, got %s", rel, want[rel], got)
+		}
+		alone, err := scanner.ScanProject(context.Background(), project.Path)
+		if err != nil {
+			t.Fatalf("ScanProject failed: %v", err)
+		}
+		if got := itemPaths(project.Path, alone.Items); got != want[rel] {
+			t.Errorf("%s: expected ScanProject items %s, got %s", rel, want[rel], got)
+		}
+	}
+}
+
+func TestNestedCacheDirectoriesCountOnce(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"package.json":                        `{}`,
+		"dist/node_modules/lib/index.js":      "bundled dependency",
+		"dist/main.js":                        "bundle",
+		"src/node_modules/local/package.json": "vendored",
+	})
+
+	scanner := newTestScanner()
+	items, err := scanner.FindCacheItems(context.Background(), root, scanner.DetectProjectType(root).CacheConfig)
+	if err != nil {
+		t.Fatalf("FindCacheItems failed: %v", err)
+	}
+	// dist/node_modules is part of dist, not an item of its own
+	if got, want := itemPaths(root, items), "dist,src/node_modules"; got != want {
+		t.Errorf("Expected items %s, got %s", want, got)
+	}
+
+	size, apparent := dirSize(filepath.Join(root, "dist"))
+	for _, item := range items {
+		if filepath.Base(item.Path) == "dist" && (item.Size != size || item.ApparentSize != apparent) {
+			t.Errorf("Expected dist to be sized like dirSize (%d, %d), got %+v", size, apparent, item)
+		}
+	}
+}
+
+func TestScanReportsEachProjectWhenComplete(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"shop/package.json":                            `{"workspaces": ["packages/*"]}`,
+		"shop/packages/ui/package.json":                `{}`,
+		"shop/packages/ui/node_modules/x/package.json": "x",
+		"api/requirements.txt":                         "flask",
+		"api/__pycache__/app.pyc":                      "bytecode",
+	})
+
+	scanner := newTestScanner()
+	scanned := make(map[string]Project)
+	scanner.OnEvent = func(event Event) {
+		if event.Kind == EventProjectScanned {
+			scanned[relSlash(root, event.Path)] = *event.Project
+		}
+	}
+	projects, err := scanner.Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	// Workspace members are part of their root, not reported on their own
+	if len(scanned) != len(projects) {
+		t.Fatalf("Expected %d projects reported, got %v", len(projects), scanned)
+	}
+	for _, project := range projects {
+		reported, ok := scanned[relSlash(root, project.Path)]
+		if !ok || reported.TotalSize != project.TotalSize || len(reported.Items) != len(project.Items) || len(reported.Members) != len(project.Members) {
+			t.Errorf("%s: reported %+v, Scan returned %+v", project.Path, reported, project)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package cacheremover
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// workspaceMembers returns the member directories declared by the workspace
+// manifests in dir: package.json "workspaces", pnpm-workspace.yaml, a Cargo
+// [workspace], Gradle settings and go.work. Only members that are projects
+// themselves are returned, sorted by path. A directory without a workspace
+// manifest has no members.
+func (s *Scanner) workspaceMembers(dir string) []string {
+	var include, exclude []string
+	for _, manifest := range []func(string) ([]string, []string){
+		npmWorkspaces,
+		pnpmWorkspaces,
+		cargoWorkspace,
+		gradleSettings,
+		goWork,
+	} {
+		in, ex := manifest(dir)
+		include = append(include, in...)
+		exclude = append(exclude, ex...)
+	}
+	if len(include) == 0 {
+		return nil
+	}
+
+	excluded := make(map[string]bool)
+	for _, pattern := range exclude {
+		for _, member := range s.expandMembers(dir, pattern) {
+			excluded[member] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var members []string
+	for _, pattern := range include {
+		for _, member := range s.expandMembers(dir, pattern) {
+			if seen[member] || excluded[member] || !s.IsProjectDirectory(member) {
+				continue
+			}
+			seen[member] = true
+			members = append(members, member)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// expandMembers resolves a member pattern such as "packages/*" or
+// "crates/**" to the directories below dir that it matches
+func (s *Scanner) expandMembers(dir, pattern string) []string {
+	clean := path.Clean(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || path.IsAbs(clean) {
+		// Members outside the workspace are scanned as projects of their own
+		return nil
+	}
+	return s.globDirs(dir, strings.Split(clean, "/"))
+}
+
+func (s *Scanner) globDirs(dir string, segments []string) []string {
+	if len(segments) == 0 {
+		return []string{dir}
+	}
+	segment, rest := segments[0], segments[1:]
+
+	if segment == "**" {
+		// Zero or more directory levels
+		matches := s.globDirs(dir, rest)
+		for _, sub := range s.subdirs(dir) {
+			matches = append(matches, s.globDirs(sub, segments)...)
+		}
+		return matches
+	}
+
+	if !strings.ContainsAny(segment, `*?[\`) {
+		next := filepath.Join(dir, segment)
+		if info, err := os.Stat(next); err != nil || !info.IsDir() {
+			return nil
+		}
+		return s.globDirs(next, rest)
+	}
+
+	var matches []string
+	for _, sub := range s.subdirs(dir) {
+		if ok, _ := path.Match(segment, filepath.Base(sub)); ok {
+			matches = append(matches, s.globDirs(sub, rest)...)
+		}
+	}
+	return matches
+}
+
+// subdirs lists the directories in dir that may contain workspace members,
+// leaving out hidden and cache directories
+func (s *Scanner) subdirs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() && !strings.HasPrefix(name, ".") && !s.IsCacheDirectory(name) {
+			dirs = append(dirs, filepath.Join(dir, name))
+		}
+	}
+	return dirs
+}
+
+// npmWorkspaces reads the "workspaces" field of package.json, which is used
+// by npm, yarn and bun. Yarn also accepts {"packages": [...]}.
+func npmWorkspaces(dir string) (include, exclude []string) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, nil
+	}
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(data, &manifest) != nil || manifest.Workspaces == nil {
+		return nil, nil
+	}
+
+	var patterns []string
+	if json.Unmarshal(manifest.Workspaces, &patterns) != nil {
+		var yarn struct {
+			Packages []string `json:"packages"`
+		}
+		json.Unmarshal(manifest.Workspaces, &yarn)
+		patterns = yarn.Packages
+	}
+	return splitNegated(patterns)
+}
+
+// pnpmWorkspaces reads the packages list of pnpm-workspace.yaml
+func pnpmWorkspaces(dir string) (include, exclude []string) {
+	lines := readLines(filepath.Join(dir, "pnpm-workspace.yaml"))
+
+	var patterns []string
+	inPackages := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(stripComment(line, "#"))
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "-") {
+			// A top-level key
+			inPackages = strings.HasPrefix(trimmed, "packages:")
+			continue
+		}
+		if inPackages && strings.HasPrefix(trimmed, "-") {
+			patterns = append(patterns, unquote(strings.TrimSpace(trimmed[1:])))
+		}
+	}
+	return splitNegated(patterns)
+}
+
+// cargoWorkspace reads the members and exclude arrays of the [workspace]
+// table in Cargo.toml
+func cargoWorkspace(dir string) (include, exclude []string) {
+	lines := readLines(filepath.Join(dir, "Cargo.toml"))
+
+	inWorkspace := false
+	var key, value string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(stripComment(line, "#"))
+		if strings.HasPrefix(trimmed, "[") && key == "" {
+			inWorkspace = trimmed == "[workspace]"
+			continue
+		}
+		if !inWorkspace {
+			continue
+		}
+
+		if key == "" {
+			name, rest, ok := strings.Cut(trimmed, "=")
+			name = strings.TrimSpace(name)
+			if !ok || (name != "members" && name != "exclude") {
+				continue
+			}
+			key, value = name, ""
+			trimmed = strings.TrimSpace(rest)
+		}
+		// Arrays may span several lines
+		value += " " + trimmed
+		if !strings.Contains(trimmed, "]") {
+			continue
+		}
+
+		if key == "members" {
+			include = append(include, quotedStrings(value)...)
+		} else {
+			exclude = append(exclude, quotedStrings(value)...)
+		}
+		key = ""
+	}
+	return include, exclude
+}
+
+// gradleSettings reads the include statements of settings.gradle or
+// settings.gradle.kts. Project paths such as ":libs:core" map to the
+// directory libs/core.
+func gradleSettings(dir string) (include, exclude []string) {
+	lines := readLines(filepath.Join(dir, "settings.gradle"))
+	if lines == nil {
+		lines = readLines(filepath.Join(dir, "settings.gradle.kts"))
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(stripComment(line, "//"))
+		if !strings.HasPrefix(trimmed, "include") || strings.HasPrefix(trimmed, "includeBuild") {
+			continue
+		}
+		for _, project := range quotedStrings(trimmed) {
+			project = strings.Trim(project, ":")
+			if project != "" {
+				include = append(include, strings.ReplaceAll(project, ":", "/"))
+			}
+		}
+	}
+	return include, nil
+}
+
+// goWork reads the use directives of go.work
+func goWork(dir string) (include, exclude []string) {
+	lines := readLines(filepath.Join(dir, "go.work"))
+
+	inBlock := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(stripComment(line, "//"))
+		switch {
+		case inBlock && trimmed == ")":
+			inBlock = false
+		case inBlock && trimmed != "":
+			include = append(include, unquote(trimmed))
+		case trimmed == "use (":
+			inBlock = true
+		case strings.HasPrefix(trimmed, "use "):
+			include = append(include, unquote(strings.TrimSpace(trimmed[len("use "):])))
+		}
+	}
+	return include, nil
+}
+
+// splitNegated separates patterns starting with "!" from the others
+func splitNegated(patterns []string) (include, exclude []string) {
+	for _, p := range patterns {
+		if negated, ok := strings.CutPrefix(p, "!"); ok {
+			exclude = append(exclude, negated)
+		} else if p != "" {
+			include = append(include, p)
+		}
+	}
+	return include, exclude
+}
+
+// readLines returns the lines of a file, or nil if it cannot be read
+func readLines(filePath string) []string {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// stripComment removes a trailing comment that starts outside quotes
+func stripComment(line, marker string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(line[i:], marker):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// quotedStrings returns the contents of every single- or double-quoted
+// string in s
+func quotedStrings(s string) []string {
+	var values []string
+	for {
+		start := strings.IndexAny(s, `"'`)
+		if start < 0 {
+			return values
+		}
+		end := strings.IndexByte(s[start+1:], s[start])
+		if end < 0 {
+			return values
+		}
+		values = append(values, s[start+1:start+1+end])
+		s = s[start+end+2:]
+	}
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package cacheremover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWorkspaceMembers(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "npm",
+			files: map[string]string{
+				"package.json":                  `{"workspaces": ["packages/*", "!packages/legacy"]}`,
+				"packages/ui/package.json":      `{}`,
+				"packages/api/package.json":     `{}`,
+				"packages/legacy/package.json":  `{}`,
+				"packages/notes/README.md":      ``,
+				"packages/ui/node_modules/x.js": ``,
+			},
+			want: []string{"packages/api", "packages/ui"},
+		},
+		{
+			name: "yarn",
+			files: map[string]string{
+				"package.json":           `{"workspaces": {"packages": ["apps/**"]}}`,
+				"apps/web/package.json":  `{}`,
+				"apps/web/src/index.js":  ``,
+				"apps/a/b/package.json":  `{}`,
+				"apps/web/.next/x.json":  ``,
+				"apps/web/node_modules/": ``,
+			},
+			want: []string{"apps/a/b", "apps/web"},
+		},
+		{
+			name: "pnpm",
+			files: map[string]string{
+				"package.json":               `{}`,
+				"pnpm-workspace.yaml":        "packages:\n  - 'packages/*' # all packages\n  - \"!packages/skip\"\ncatalog:\n  - other\n",
+				"packages/a/package.json":    `{}`,
+				"packages/skip/package.json": `{}`,
+				"other/package.json":         `{}`,
+			},
+			want: []string{"packages/a"},
+		},
+		{
+			name: "cargo",
+			files: map[string]string{
+				"Cargo.toml":             "[workspace]\nmembers = [\n  \"crates/*\",\n  \"tool\", # the CLI\n]\nexclude = [\"crates/old\"]\n\n[workspace.dependencies]\nserde = \"1\"\n",
+				"crates/core/Cargo.toml": ``,
+				"crates/old/Cargo.toml":  ``,
+				"tool/Cargo.toml":        ``,
+			},
+			want: []string{"crates/core", "tool"},
+		},
+		{
+			name: "gradle",
+			files: map[string]string{
+				"settings.gradle.kts":          "rootProject.name = \"app\"\ninclude(\":app\", \":libs:core\")\n// include(\":disabled\")\nincludeBuild(\"build-logic\")\n",
+				"app/build.gradle.kts":         ``,
+				"libs/core/build.gradle.kts":   ``,
+				"disabled/build.gradle.kts":    ``,
+				"build-logic/build.gradle.kts": ``,
+			},
+			want: []string{"app", "libs/core"},
+		},
+		{
+			name: "go",
+			files: map[string]string{
+				"go.work":    "go 1.22\n\nuse (\n\t./cmd // tools\n\t./lib\n)\nuse ../outside\n",
+				"cmd/go.mod": ``,
+				"lib/go.mod": ``,
+			},
+			want: []string{"cmd", "lib"},
+		},
+	}
+
+	scanner := newTestScanner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+
+			var got []string
+			for _, member := range scanner.workspaceMembers(root) {
+				got = append(got, relSlash(root, member))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected members %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScanWorkspace(t *testing.T) {
+	scanner := newTestScanner()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"package.json":                                 `{"workspaces": ["packages/*"]}`,
+		"node_modules/react/package.json":              `root`,
+		"packages/ui/package.json":                     `{}`,
+		"packages/ui/node_modules/.package-lock.json":  `ui`,
+		"packages/ui/dist/index.js":                    `ui`,
+		"packages/api/package.json":                    `{}`,
+		"packages/api/node_modules/.package-lock.json": `api`,
+	})
+
+	paths, err := scanner.FindProjects(context.Background(), root)
+	if err != nil {
+		t.Fatalf("FindProjects failed: %v", err)
+	}
+	var found []string
+	for _, path := range paths {
+		found = append(found, relSlash(root, path))
+	}
+	// Members are reported through the workspace root only
+	if want := []string{"."}; !reflect.DeepEqual(found, want) {
+		t.Fatalf("Expected projects %v, got %v", want, found)
+	}
+
+	project, err := scanner.ScanProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("ScanProject failed: %v", err)
+	}
+	if len(project.Members) != 2 {
+		t.Fatalf("Expected 2 workspace members, got %d", len(project.Members))
+	}
+
+	// Every item is attributed once: to the root or to one member
+	seen := make(map[string]int)
+	for _, item := range project.Items {
+		seen[relSlash(root, item.Path)]++
+	}
+	want := map[string]int{
+		"node_modules":              1,
+		"packages/api/node_modules": 1,
+		"packages/ui/node_modules":  1,
+		"packages/ui/dist":          1,
+	}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("Expected items %v, got %v", want, seen)
+	}
+
+	var total int64
+	for _, member := range project.Members {
+		total += member.TotalSize
+	}
+	if ui := project.Members[1]; relSlash(root, ui.Path) != "packages/ui" || len(ui.Items) != 2 {
+		t.Errorf("Expected 2 items for packages/ui, got %v", ui.Items)
+	}
+	if project.TotalSize <= total {
+		t.Errorf("Workspace total %d should include the root's own items beyond the members' %d", project.TotalSize, total)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cache-remover-utility/cacheremover"
+)
+
+// runCommand dispatches subcommands such as `restore`, `purge`, `history`
+// and `tag`. It reports false when args do not start with a known subcommand.
+func runCommand(config *cacheremover.Config, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "restore":
+		return true, runRestore(config, args[1:])
+	case "purge":
+		return true, runPurge(config, args[1:])
+	case "history":
+		return true, runHistory(config, args[1:])
+	case "tag":
+		return true, runTag(config, args[1:])
+	default:
+		return false, nil
+	}
+}
+
+// newTrash opens the trash directory configured in settings
+func newTrash(config *cacheremover.Config) *cacheremover.Trash {
+	dir := config.Settings.TrashDir
+	if dir == "" {
+		dir = cacheremover.DefaultTrashDir()
+	}
+	return cacheremover.NewTrash(dir, cacheremover.DefaultTrashManifest(dir))
+}
+
+func runRestore(config *cacheremover.Config, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cache-remover restore [ID|ORIGINAL-PATH]...\n\n")
+		fmt.Fprintf(flags.Output(), "Without arguments, lists the cache items currently in the trash.\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	trash := newTrash(config)
+
+	if flags.NArg() == 0 {
+		entries, err := trash.Entries()
+		if err != nil {
+			return err
+		}
+		printTrashEntries(trash, entries)
+		return nil
+	}
+
+	failed := 0
+	for _, ref := range flags.Args() {
+		entry, err := trash.Restore(ref)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("♻️  Restored %s (%s)\n", entry.OriginalPath, formatBytes(entry.Size))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d items could not be restored", failed, flags.NArg())
+	}
+	return nil
+}
+
+func runPurge(config *cacheremover.Config, args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", "Only purge items trashed longer ago than this (e.g. 30d, 2w, 12h)")
+	all := flags.Bool("all", false, "Purge every item in the trash")
+	flags.Parse(args)
+
+	if *olderThan == "" && !*all {
+		return fmt.Errorf("purge requires --older-than or --all")
+	}
+
+	cutoff := time.Now()
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return err
+		}
+		cutoff = cutoff.Add(-age)
+	}
+
+	trash := newTrash(config)
+	purged, err := trash.Purge(cutoff)
+
+	var size int64
+	for _, entry := range purged {
+		size += entry.Size
+	}
+	fmt.Printf("🔥 Purged %d items (%s) from %s\n", len(purged), formatBytes(size), trash.Dir())
+	return err
+}
+
+// runTag writes a CACHEDIR.TAG into every cache directory found below the
+// given directory, so that backup software skips them
+func runTag(config *cacheremover.Config, args []string) error {
+	flags := flag.NewFlagSet("tag", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "Show which directories would be tagged")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cache-remover tag [-dry-run] [DIRECTORY]\n\n")
+		fmt.Fprintf(flags.Output(), "Marks the cache directories of every project with a %s file.\n", cacheremover.CacheDirTagName)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	rootDir := "."
+	if flags.NArg() > 0 {
+		rootDir = flags.Arg(0)
+	}
+
+	ctx := context.Background()
+	scanner := cacheremover.NewScanner(config)
+	projects, err := scanner.Scan(ctx, rootDir)
+	if err != nil {
+		return err
+	}
+
+	tagged, failed := 0, 0
+	for _, project := range projects {
+		for _, item := range project.Items {
+			if item.Type != "directory" || cacheremover.HasCacheDirTag(item.Path) {
+				continue
+			}
+			if *dryRun {
+				fmt.Printf("🏷️  Would tag %s\n", item.Path)
+				tagged++
+				continue
+			}
+			if _, err := cacheremover.WriteCacheDirTag(item.Path); err != nil {
+				fmt.Printf("❌ Cannot tag %s: %v\n", item.Path, err)
+				failed++
+				continue
+			}
+			fmt.Printf("🏷️  Tagged %s\n", item.Path)
+			tagged++
+		}
+	}
+
+	verb := "Tagged"
+	if *dryRun {
+		verb = "Would tag"
+	}
+	fmt.Printf("\n%s %d cache directories in %d projects\n", verb, tagged, len(projects))
+	if failed > 0 {
+		return fmt.Errorf("%d directories could not be tagged", failed)
+	}
+	return nil
+}
+
+func printTrashEntries(trash *cacheremover.Trash, entries []cacheremover.TrashEntry) {
+	if len(entries) == 0 {
+		fmt.Printf("🗑️  Trash is empty (%s)\n", trash.Dir())
+		return
+	}
+
+	var size int64
+	fmt.Printf("🗑️  %d items in %s:\n\n", len(entries), trash.Dir())
+	for _, entry := range entries {
+		size += entry.Size
+		fmt.Printf("  %s  %-10s %-12s %s\n     id: %s\n",
+			entry.TrashedAt.Format("2006-01-02 15:04"),
+			formatBytes(entry.Size),
+			entry.ProjectType,
+			entry.OriginalPath,
+			entry.ID)
+		if entry.Partial {
+			fmt.Printf("     ⚠️  partly left in place; restoring replaces what is left\n")
+		}
+	}
+	fmt.Printf("\n   Total: %s\n", formatBytes(size))
+}
+
+// parseAge parses durations such as "30d", "2w" or "12h". A bare number is
+// taken as a number of days.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+
+	if n, err := strconv.ParseFloat(s, 64); err == nil && n >= 0 {
+		return time.Duration(n * float64(units["d"])), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", s)
+	}
+	return d, nil
+}
+
+// parseSize parses sizes such as "500M", "20G" or "1.5GB" using the same
+// 1024-based units as formatBytes. A bare number is a number of bytes.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I") // Accept "GB" and "GiB"
+
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", s[n-1]); exp >= 0 {
+			for i := 0; i <= exp; i++ {
+				multiplier *= 1024
+			}
+			s = s[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size (use e.g. 500M or 20G)")
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// exitOnCommandError prints a subcommand error and exits non-zero
+func exitOnCommandError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
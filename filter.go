@@ -0,0 +1,95 @@
+package main
+
+import (
+	"time"
+
+	"cache-remover-utility/cacheremover"
+)
+
+// projectFilter decides which scanned projects a run considers. It is
+// shared by the CLI workers and the TUI loader.
+type projectFilter struct {
+	// olderThan excludes projects with activity more recent than this
+	olderThan time.Duration
+	// minItemSize drops cache items smaller than this many bytes
+	minItemSize int64
+	// minProjectSize excludes projects whose cache totals less than this
+	minProjectSize int64
+}
+
+// configure enables the scanner measurements the filter relies on
+func (f projectFilter) configure(scanner *cacheremover.Scanner) {
+	if f.olderThan > 0 {
+		scanner.MeasureActivity = true
+	}
+}
+
+// isActive reports whether project was worked on within the olderThan window
+func (f projectFilter) isActive(project *cacheremover.Project) bool {
+	if f.olderThan <= 0 || project.LastActivity.IsZero() {
+		return false
+	}
+	return time.Since(project.LastActivity) < f.olderThan
+}
+
+// dropSmallItems removes cache items below minItemSize from project and
+// its workspace members and updates their total sizes
+func (f projectFilter) dropSmallItems(project *cacheremover.Project) {
+	if f.minItemSize <= 0 {
+		return
+	}
+	for _, member := range project.Members {
+		f.dropSmallItems(member)
+	}
+
+	kept := project.Items[:0]
+	project.TotalSize, project.ApparentSize = 0, 0
+	for _, item := range project.Items {
+		if item.Size < f.minItemSize {
+			continue
+		}
+		kept = append(kept, item)
+		project.TotalSize += item.Size
+		project.ApparentSize += item.ApparentSize
+	}
+	project.Items = kept
+}
+
+// isTooSmall reports whether project has cache below minProjectSize. Projects
+// without any cache are not too small; they are simply clean.
+func (f projectFilter) isTooSmall(project *cacheremover.Project) bool {
+	return f.minProjectSize > 0 && len(project.Items) > 0 && project.TotalSize < f.minProjectSize
+}
+
+// include applies the whole filter to a scanned project, dropping small
+// items, and reports whether the project remains part of the run
+func (f projectFilter) include(project *cacheremover.Project) bool {
+	if f.isActive(project) {
+		return false
+	}
+	f.dropSmallItems(project)
+	return !f.isTooSmall(project)
+}
+
+// filtered applies the whole filter to a copy of project, for projects that
+// must not be modified, such as those reported while a scan runs. It
+// returns nil when the project is not part of the run.
+func (f projectFilter) filtered(project *cacheremover.Project) *cacheremover.Project {
+	project = cloneProject(project)
+	if !f.include(project) {
+		return nil
+	}
+	return project
+}
+
+// cloneProject copies project along with the items and members the filter
+// may change
+func cloneProject(project *cacheremover.Project) *cacheremover.Project {
+	clone := *project
+	clone.Items = append([]cacheremover.CacheItem(nil), project.Items...)
+	clone.Members = nil
+	for _, member := range project.Members {
+		clone.Members = append(clone.Members, cloneProject(member))
+	}
+	return &clone
+}
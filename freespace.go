@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"cache-remover-utility/cacheremover"
+)
+
+// freeThreshold is a free-space level, either a percentage of the
+// filesystem ("10%") or an absolute size ("50G")
+type freeThreshold struct {
+	percent float64
+	bytes   int64
+}
+
+func parseFreeThreshold(s string) (freeThreshold, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return freeThreshold{}, fmt.Errorf("invalid percentage %q", s)
+		}
+		return freeThreshold{percent: percent}, nil
+	}
+
+	size, err := parseSize(s)
+	if err != nil || size <= 0 {
+		return freeThreshold{}, fmt.Errorf("invalid free space %q (use e.g. 10%% or 50G)", s)
+	}
+	return freeThreshold{bytes: size}, nil
+}
+
+// bytesOf returns the threshold in bytes for a filesystem of the given size
+func (t freeThreshold) bytesOf(total uint64) int64 {
+	if t.percent > 0 {
+		return int64(float64(total) * t.percent / 100)
+	}
+	return t.bytes
+}
+
+// double returns the default high-water mark for a low-water mark
+func (t freeThreshold) double() freeThreshold {
+	if t.percent > 0 {
+		return freeThreshold{percent: min(t.percent*2, 100)}
+	}
+	return freeThreshold{bytes: t.bytes * 2}
+}
+
+func (t freeThreshold) String() string {
+	if t.percent > 0 {
+		return strconv.FormatFloat(t.percent, 'f', -1, 64) + "%"
+	}
+	return formatBytes(t.bytes)
+}
+
+// freeSpaceTarget returns how many bytes must be reclaimed to bring free
+// space back up to high, or 0 when it has not dropped below low
+func freeSpaceTarget(usage cacheremover.DiskUsage, low, high freeThreshold) int64 {
+	available := int64(usage.Available)
+	if available >= low.bytesOf(usage.Total) {
+		return 0
+	}
+	return high.bytesOf(usage.Total) - available
+}
+
+// freeSpaceReport is what --when-free-below found, as reported in JSON
+// output. Target is 0 when free space is not below the threshold.
+type freeSpaceReport struct {
+	Total            uint64  `json:"total"`
+	Available        uint64  `json:"available"`
+	AvailablePercent float64 `json:"available_percent"`
+	Below            string  `json:"below"`
+	Until            string  `json:"until"`
+	Target           int64   `json:"target"`
+}
+
+// checkFreeSpace implements --when-free-below: it inspects the filesystem
+// holding rootDir and returns what it found, with the number of bytes to
+// reclaim as Target, which is 0 when there is enough free space and the run
+// should stop. Status messages go to w.
+func checkFreeSpace(w io.Writer, rootDir, below, until string) (*freeSpaceReport, error) {
+	low, err := parseFreeThreshold(below)
+	if err != nil {
+		return nil, fmt.Errorf("--when-free-below: %v", err)
+	}
+	high := low.double()
+	if until != "" {
+		if high, err = parseFreeThreshold(until); err != nil {
+			return nil, fmt.Errorf("--free-until: %v", err)
+		}
+	}
+
+	usage, err := cacheremover.DiskUsageOf(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check free space of %s: %v", rootDir, err)
+	}
+	if high.bytesOf(usage.Total) < low.bytesOf(usage.Total) {
+		return nil, fmt.Errorf("--free-until %s is below --when-free-below %s", high, low)
+	}
+
+	report := &freeSpaceReport{
+		Total:            usage.Total,
+		Available:        usage.Available,
+		AvailablePercent: usage.AvailablePercent(),
+		Below:            low.String(),
+		Until:            high.String(),
+		Target:           freeSpaceTarget(usage, low, high),
+	}
+	available := formatBytes(int64(usage.Available))
+	if report.Target == 0 {
+		fmt.Fprintf(w, "✅ %s (%.1f%%) free on the filesystem of %s, not below %s - nothing to do\n",
+			available, usage.AvailablePercent(), rootDir, low)
+		return report, nil
+	}
+
+	fmt.Fprintf(w, "💾 %s (%.1f%%) free on the filesystem of %s, below %s - reclaiming %s to reach %s\n",
+		available, usage.AvailablePercent(), rootDir, low, formatBytes(report.Target), high)
+	return report, nil
+}
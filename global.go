@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"cache-remover-utility/cacheremover"
+)
+
+// Global cache statuses, in addition to the project statuses
+const (
+	statusMissing = "missing" // The toolchain is not installed or has no cache
+)
+
+// globalReport is the outcome of processing one global cache
+type globalReport struct {
+	Name          string                   `json:"name"`
+	Path          string                   `json:"path"`
+	Strategy      string                   `json:"strategy"`
+	UnusedDays    int                      `json:"unused_days,omitempty"`
+	Status        string                   `json:"status"`
+	Size          int64                    `json:"size"`
+	Entries       int                      `json:"entries"`
+	LastUsed      *time.Time               `json:"last_used,omitempty"`
+	NoAccessTimes bool                     `json:"no_access_times,omitempty"`
+	StaleItems    []cacheremover.CacheItem `json:"stale_items"`
+	StaleSize     int64                    `json:"stale_size"`
+	RemovedItems  int                      `json:"removed_items"`
+	RemovedSize   int64                    `json:"removed_size"`
+	Failures      []failureReport          `json:"failures,omitempty"`
+}
+
+func newGlobalReport(usage *cacheremover.GlobalCacheUsage) globalReport {
+	report := globalReport{
+		Name:          usage.Cache.Name,
+		Path:          usage.Path,
+		Strategy:      usage.Cache.Strategy,
+		UnusedDays:    usage.Cache.UnusedDays,
+		Status:        statusClean,
+		Size:          usage.Size,
+		Entries:       usage.Entries,
+		StaleItems:    usage.Stale,
+		StaleSize:     usage.StaleSize,
+		NoAccessTimes: usage.NoAccessTimes,
+	}
+	if report.Path == "" {
+		report.Path = usage.Cache.Path
+	}
+	if report.StaleItems == nil {
+		report.StaleItems = []cacheremover.CacheItem{}
+	}
+	if !usage.Exists {
+		report.Status = statusMissing
+	}
+	if !usage.LastUsed.IsZero() {
+		lastUsed := usage.LastUsed
+		report.LastUsed = &lastUsed
+	}
+	return report
+}
+
+// runGlobal implements --global: it sizes every configured toolchain cache
+// and prunes the entries selected by its strategy
+func runGlobal(ctx context.Context, config *cacheremover.Config, cleaner *cacheremover.Cleaner, history *cacheremover.History, opts cleanOptions, format string, w io.Writer) {
+	startTime := time.Now()
+	stats := &cacheremover.CleanupStats{}
+	home, _ := os.UserHomeDir()
+
+	var record *cacheremover.HistoryRecord
+	if !opts.dryRun {
+		record = cacheremover.NewHistoryRecord(home, "global")
+	}
+
+	if format == outputText {
+		fmt.Fprintf(w, "🌐 Global toolchain caches\n")
+		if opts.dryRun {
+			fmt.Fprintf(w, "🔍 DRY RUN MODE - No files will be removed\n")
+		}
+		fmt.Fprintln(w)
+	}
+
+	var reports []globalReport
+	enc := json.NewEncoder(w)
+	for _, cache := range config.GlobalCaches {
+		if ctx.Err() != nil {
+			break
+		}
+
+		usage, err := cacheremover.ScanGlobalCache(ctx, cache, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: cannot scan %s: %v\n", cache.Name, err)
+			continue
+		}
+		report := newGlobalReport(usage)
+		if format == outputText {
+			printGlobalUsage(w, usage, opts.verbose)
+		} else if usage.NoAccessTimes {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: %s: access times are not recorded (noatime?), nothing selected as unused\n", cache.Name)
+		}
+
+		switch {
+		case len(usage.Stale) == 0:
+		case opts.interactive && !opts.dryRun && !confirm(fmt.Sprintf("Prune %d entries (%s) from %s?",
+			len(usage.Stale), formatBytes(usage.StaleSize), cache.Name)):
+			report.Status = statusSkipped
+		case opts.dryRun:
+			report.Status = statusWouldRemove
+			stats.Add(len(usage.Stale), usage.StaleSize, usage.StaleApparentSize)
+		default:
+			result, _ := cleaner.CleanGlobalCache(ctx, usage)
+			report.Status = statusRemoved
+			report.RemovedItems = len(result.Removed)
+			report.RemovedSize = result.BytesRemoved
+			for _, failure := range result.Failed {
+				report.Failures = append(report.Failures, newFailureReport(failure))
+			}
+			stats.Add(len(result.Removed), result.BytesRemoved, result.ApparentBytesRemoved)
+			record.AddProject(usage.Path, cache.Name, result)
+		}
+		if usage.Exists {
+			stats.IncrementProjects()
+		}
+
+		switch format {
+		case outputText:
+			printGlobalResult(w, report)
+		case outputNDJSON:
+			enc.Encode(struct {
+				Event string `json:"event"`
+				globalReport
+			}{"global_cache", report})
+		}
+		reports = append(reports, report)
+	}
+
+	stats.ProcessingTime = time.Since(startTime)
+	if record != nil && len(record.Projects) > 0 {
+		record.Duration = stats.ProcessingTime
+		if err := history.Append(record); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: cannot write history to %s: %v\n", history.Path(), err)
+		}
+	}
+
+	switch format {
+	case outputText:
+		verb := "Removed"
+		if opts.dryRun {
+			verb = "Would remove"
+		}
+		fmt.Fprintf(w, "\n📊 %d global caches found. %s %d entries (%s) in %v\n",
+			stats.TotalProjects, verb, stats.TotalCacheItems, formatBytes(stats.TotalSizeRemoved), stats.ProcessingTime.Round(time.Millisecond))
+	case outputJSON:
+		enc.SetIndent("", "  ")
+		enc.Encode(struct {
+			DryRun bool                       `json:"dry_run"`
+			Caches []globalReport             `json:"caches"`
+			Stats  *cacheremover.CleanupStats `json:"stats"`
+		}{opts.dryRun, reports, stats})
+	case outputNDJSON:
+		enc.Encode(ndjsonSummary{Event: "summary", Stats: stats})
+	}
+}
+
+// printGlobalUsage prints the size of a global cache and what its strategy selects
+func printGlobalUsage(w io.Writer, usage *cacheremover.GlobalCacheUsage, verbose bool) {
+	cache := usage.Cache
+	if !usage.Exists {
+		if verbose {
+			fmt.Fprintf(w, "➖ %s: not found (%s)\n", cache.Name, cache.Path)
+		}
+		return
+	}
+
+	lastUsed := ""
+	if !usage.LastUsed.IsZero() {
+		lastUsed = ", last used " + formatAge(time.Since(usage.LastUsed)) + " ago"
+	}
+	fmt.Fprintf(w, "📦 %s: %s in %d entries%s\n   %s\n", cache.Name, formatBytes(usage.Size), usage.Entries, lastUsed, usage.Path)
+
+	switch {
+	case usage.Entries == 0:
+		fmt.Fprintf(w, "   ✅ Empty\n")
+	case usage.NoAccessTimes:
+		fmt.Fprintf(w, "   ⚠️  Access times are not recorded here (noatime?), so unused entries cannot be told apart; kept\n")
+	case len(usage.Stale) == 0 && cache.Strategy == cacheremover.GlobalStrategyAll:
+		fmt.Fprintf(w, "   ✅ Used within %d days, kept\n", cache.UnusedDays)
+	case len(usage.Stale) == 0:
+		fmt.Fprintf(w, "   ✅ Nothing unused for %d days\n", cache.UnusedDays)
+	case cache.Strategy == cacheremover.GlobalStrategyAll:
+		fmt.Fprintf(w, "   🧹 Whole cache selected (%s)\n", formatBytes(usage.StaleSize))
+	default:
+		fmt.Fprintf(w, "   🧹 %d entries (%s) unused for %d days\n", len(usage.Stale), formatBytes(usage.StaleSize), cache.UnusedDays)
+	}
+	if verbose {
+		for _, item := range usage.Stale {
+			fmt.Fprintf(w, "  - %s (%s)\n", item.Path, formatBytes(item.Size))
+		}
+	}
+}
+
+func printGlobalResult(w io.Writer, report globalReport) {
+	switch report.Status {
+	case statusWouldRemove:
+		fmt.Fprintf(w, "   🔍 Would remove %d entries (%s)\n", len(report.StaleItems), formatBytes(report.StaleSize))
+	case statusSkipped:
+		fmt.Fprintf(w, "   ⏭️  Skipped\n")
+	case statusRemoved:
+		fmt.Fprintf(w, "   ✅ Removed %d entries (%s)\n", report.RemovedItems, formatBytes(report.RemovedSize))
+		for _, failure := range report.Failures {
+			fmt.Fprintf(w, "   ❌ %s: %s\n", failure.Path, failure.Error)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cache-remover-utility/cacheremover"
+)
+
+// newHistory opens the history file configured in settings
+func newHistory(config *cacheremover.Config) *cacheremover.History {
+	path := config.Settings.HistoryFile
+	if path == "" {
+		path = cacheremover.DefaultHistoryPath()
+	}
+	return cacheremover.NewHistory(path)
+}
+
+// historyReporter wraps another reporter and appends a history record for
+// every real (non dry-run) clean when the run ends
+type historyReporter struct {
+	reporter
+	history *cacheremover.History
+
+	mu     sync.Mutex
+	record *cacheremover.HistoryRecord
+}
+
+func (r *historyReporter) begin(rootDir string, opts cleanOptions, config *cacheremover.Config) {
+	if !opts.dryRun {
+		r.record = cacheremover.NewHistoryRecord(rootDir, "cli")
+	}
+	r.reporter.begin(rootDir, opts, config)
+}
+
+func (r *historyReporter) projectFinished(report projectReport) {
+	if r.record != nil && report.result != nil {
+		r.mu.Lock()
+		r.record.AddProject(report.Path, report.Type, report.result)
+		r.mu.Unlock()
+	}
+	r.reporter.projectFinished(report)
+}
+
+func (r *historyReporter) end(stats *cacheremover.CleanupStats) {
+	if r.record != nil && len(r.record.Projects) > 0 {
+		r.record.Duration = stats.ProcessingTime
+		if err := r.history.Append(r.record); err != nil {
+			r.reporter.warning("cannot write history to %s: %v", r.history.Path(), err)
+		}
+	}
+	r.reporter.end(stats)
+}
+
+func runHistory(config *cacheremover.Config, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "Number of most recent runs to list")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cache-remover history [--limit N] [RUN-ID]\n\n")
+		fmt.Fprintf(flags.Output(), "Without arguments, lists past cleanup runs and totals reclaimed per month.\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	history := newHistory(config)
+
+	if flags.NArg() > 0 {
+		record, err := history.Find(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		printHistoryRecord(record)
+		return nil
+	}
+
+	records, err := history.Records()
+	if err != nil {
+		return err
+	}
+	printHistory(history, records, *limit)
+	return nil
+}
+
+func printHistory(history *cacheremover.History, records []cacheremover.HistoryRecord, limit int) {
+	if len(records) == 0 {
+		fmt.Printf("📜 No cleanup runs recorded yet (%s)\n", history.Path())
+		return
+	}
+
+	shown := records
+	if limit > 0 && len(shown) > limit {
+		shown = shown[len(shown)-limit:]
+	}
+
+	fmt.Printf("📜 Cleanup history (%d of %d runs):\n\n", len(shown), len(records))
+	for i := len(shown) - 1; i >= 0; i-- {
+		record := shown[i]
+		mode := ""
+		if record.Trashed {
+			mode = " [trash]"
+		}
+		failures := ""
+		if record.Failures > 0 {
+			failures = fmt.Sprintf(", %d failed", record.Failures)
+		}
+		fmt.Printf("  %s  %s  %-10s %d projects, %d items%s (%s)%s\n",
+			record.ID,
+			record.Time.Format("2006-01-02 15:04"),
+			formatBytes(record.BytesReclaimed),
+			len(record.Projects),
+			record.ItemsRemoved,
+			failures,
+			record.Source,
+			mode)
+		fmt.Printf("     %s\n", record.RootDir)
+	}
+
+	// Totals reclaimed over time, grouped by month
+	monthly := make(map[string]int64)
+	var total int64
+	for _, record := range records {
+		monthly[record.Time.Format("2006-01")] += record.BytesReclaimed
+		total += record.BytesReclaimed
+	}
+	months := make([]string, 0, len(monthly))
+	for month := range monthly {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+
+	fmt.Printf("\n📊 Reclaimed per month:\n")
+	for _, month := range months {
+		fmt.Printf("   %s  %s\n", month, formatBytes(monthly[month]))
+	}
+	fmt.Printf("   Total    %s across %d runs\n", formatBytes(total), len(records))
+	fmt.Printf("\n💡 Tip: Use 'history <id>' to see what a run removed\n")
+}
+
+func printHistoryRecord(record *cacheremover.HistoryRecord) {
+	fmt.Printf("📜 Run %s\n", record.ID)
+	fmt.Printf("   Time: %s\n", record.Time.Format(time.RFC1123))
+	fmt.Printf("   Root directory: %s\n", record.RootDir)
+	fmt.Printf("   Source: %s\n", record.Source)
+	if record.Trashed {
+		fmt.Printf("   Mode: moved to trash\n")
+	}
+	fmt.Printf("   Items removed: %d\n", record.ItemsRemoved)
+	fmt.Printf("   Space reclaimed: %s\n", formatBytes(record.BytesReclaimed))
+	fmt.Printf("   Failures: %d\n", record.Failures)
+	if record.Duration > 0 {
+		fmt.Printf("   Duration: %v\n", record.Duration)
+	}
+	fmt.Println()
+
+	for _, project := range record.Projects {
+		fmt.Printf("🗂️  %s (%s): %s\n", project.Path, project.Type, formatBytes(project.BytesRemoved))
+		for _, item := range project.Removed {
+			fmt.Printf("  - %s (%s)\n", item.Path, formatBytes(item.Size))
+		}
+		for _, failure := range project.Failed {
+			fmt.Printf("  ❌ %s: %s\n", failure.Path, failure.Error)
+		}
+	}
+}
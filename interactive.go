@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"cache-remover-utility/cacheremover"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
@@ -152,8 +154,10 @@ var keys = keyMap{
 
 type ProjectItem struct {
 	Project    *Project
+	Source     *cacheremover.Project  // Scan result the item was built from
+	Git        *cacheremover.RepoInfo // Checkout the project lives in; nil outside git
 	Selected   bool
-	CacheItems []CacheItem
+	CacheItems []cacheremover.CacheItem
 	TotalSize  int64
 	ItemCount  int
 }
@@ -176,7 +180,12 @@ func (p ProjectItem) Title() string {
 		status = warningStyle.Render(fmt.Sprintf("🗑 %s (%s)", countStr, sizeStr))
 	}
 
-	return fmt.Sprintf("%s %s [%s] - %s", icon, p.Project.Name, p.Project.Type, status)
+	idle := ""
+	if p.Source != nil && !p.Source.LastActivity.IsZero() {
+		idle = fmt.Sprintf(" (idle %s)", formatAge(time.Since(p.Source.LastActivity)))
+	}
+
+	return fmt.Sprintf("%s %s [%s]%s - %s", icon, p.Project.Name, p.Project.Type, idle, status)
 }
 
 func (p ProjectItem) Description() string {
@@ -202,13 +211,13 @@ type TreeNode struct {
 	Level          int          // Depth level (for indentation)
 	ChildProjects  int          // Number of projects in subtree
 	ChildCacheSize int64        // Total cache size in subtree
-	
+
 	// Phase 1 & 3: Enhanced metadata fields
-	FileSize       int64        // Individual file/directory size (0 if not calculated)
-	FileCount      int          // Number of files in directory (0 for files, -1 if not calculated)
-	LastModified   time.Time    // Last modification time (zero value if not set)
-	FileType       string       // "file", "directory", "project" (empty if not set)
-	IsFile         bool         // true for individual files, false for directories
+	FileSize     int64     // Individual file/directory size (0 if not calculated)
+	FileCount    int       // Number of files in directory (0 for files, -1 if not calculated)
+	LastModified time.Time // Last modification time (zero value if not set)
+	FileType     string    // "file", "directory", "project" (empty if not set)
+	IsFile       bool      // true for individual files, false for directories
 }
 
 // TreeModel manages the tree structure and display
@@ -231,61 +240,91 @@ const (
 )
 
 type model struct {
-	state       AppState
-	keys        keyMap
-	list        list.Model
-	spinner     spinner.Model
-	progress    progress.Model
-	projects    []ProjectItem
-	tree        *TreeModel // Tree structure for projects
-	useTreeView bool       // Toggle between tree and list view
-	loading     bool
-	err         error
-	rootDir     string     // Directory to scan for projects
+	state           AppState
+	keys            keyMap
+	list            list.Model
+	spinner         spinner.Model
+	progress        progress.Model
+	projects        []ProjectItem
+	tree            *TreeModel // Tree structure for projects
+	useTreeView     bool       // Toggle between tree and list view
+	loading         bool
+	err             error
+	rootDir         string // Directory to scan for projects
 	loadingProgress string // Progress message during loading
+	scanFound       int    // Projects discovered by the running scan
+	// cancelScan stops the running scan when the UI quits
+	cancelScan context.CancelFunc
+	initScan   tea.Cmd // Starts the first scan; returned by Init
+
+	scanner *cacheremover.Scanner
+	cleaner *cacheremover.Cleaner
+	history *cacheremover.History
+	filter  projectFilter // Excludes projects the run should not consider
+	workers int           // Number of projects cleaned in parallel
 
 	// Cleaning state
-	cleaningIndex     int
-	cleaningProgress  float64
-	cleaningResults   CleanupStats
-	currentProject    string
-	totalProjects     int
-	projectsCompleted int
+	cleanJob        *cleanJob        // Running clean; nil once it has finished
+	cleaning        cleanProgressMsg // Latest progress of the running clean
+	cleanCancelled  bool             // The user asked the running clean to stop
+	cleaningResults *cacheremover.CleanupStats
+	cleanOutcomes   []cleanOutcome // What the last clean did with each project
 
 	// Details view
-	detailsProject *ProjectItem
+	// detailsPath is the project shown. Projects arriving from a running
+	// scan move the others around in m.projects, so it is looked up by path.
+	detailsPath string
 
 	// Confirmation
 	confirmMessage string
-	confirmAction  func() tea.Cmd
+	confirmAction  func(m *model) tea.Cmd
 
 	width  int
 	height int
 }
 
+// loadProjectsMsg ends a scan. It carries the projects completed since
+// the last loadProgressMsg.
 type loadProjectsMsg struct {
 	projects []ProjectItem
+	found    int
 	err      error
 }
 
+// loadProgressMsg delivers what a running scan found since the previous
+// message, so the tree fills in while the scan goes on
 type loadProgressMsg struct {
-	progress string
+	stream   *scanStream
+	projects []ProjectItem // Projects whose caches have been sized
+	found    int           // Projects discovered, sized or not
 }
 
+// cleanProgressMsg is the progress of a running clean
 type cleanProgressMsg struct {
-	index          int
-	progress       float64
-	results        CleanupStats
-	currentProject string
-	totalProjects  int
-	completed      bool
+	job          *cleanJob
+	cleaning     []string // Names of the projects being cleaned
+	removing     []string // Paths of the items being removed
+	projectsDone int
+	itemsRemoved int
+	// bytesDone is the scanned size of the items dealt with, which the
+	// progress bar and the ETA compare with the size selected
+	bytesDone  int64
+	bytesFreed int64
 }
 
+// cleanCompleteMsg ends a clean, finished or cancelled
 type cleanCompleteMsg struct {
-	results CleanupStats
+	results  *cacheremover.CleanupStats
+	outcomes []cleanOutcome
 }
 
-func initialModel(rootDir string) model {
+// cleanOutcome is what a clean did with one selected project
+type cleanOutcome struct {
+	project ProjectItem
+	result  *cacheremover.CleanResult // nil when the clean stopped before the project
+}
+
+func initialModel(rootDir string, scanner *cacheremover.Scanner, cleaner *cacheremover.Cleaner, history *cacheremover.History, filter projectFilter, workers int) model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
@@ -305,82 +344,191 @@ func initialModel(rootDir string) model {
 	prog := progress.New(progress.WithDefaultGradient())
 
 	m := model{
-		state:       StateLoading,
-		keys:        keys,
-		list:        l,
-		spinner:     s,
-		progress:    prog,
-		useTreeView: true, // Enable tree view by default  
-		loading:     true,
-		rootDir:     rootDir, // Store the directory to scan
+		state:           StateLoading,
+		keys:            keys,
+		list:            l,
+		spinner:         s,
+		progress:        prog,
+		useTreeView:     true, // Enable tree view by default
+		loading:         true,
+		rootDir:         rootDir, // Store the directory to scan
+		scanner:         scanner,
+		cleaner:         cleaner,
+		history:         history,
+		filter:          filter,
+		workers:         workers,
+		cleaningResults: &cacheremover.CleanupStats{},
 	}
+	m.initScan = m.startScan()
 
 	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(
-		m.spinner.Tick,
-		loadProjects(m.rootDir), // Use the stored directory instead of hardcoded "."
-	)
+	return m.initScan
 }
 
-func loadProjects(rootDir string) tea.Cmd {
-	return tea.Cmd(func() tea.Msg {
-		projects := []ProjectItem{}
+// startScan forgets the projects shown and scans the stored directory again
+func (m *model) startScan() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelScan = cancel
+	m.loading = true
+	m.state = StateLoading
+	m.projects, m.tree, m.scanFound = nil, nil, 0
+	m.loadingProgress = ""
+	m.list.SetItems(nil)
+	return tea.Batch(m.spinner.Tick, loadProjects(ctx, m.scanner, m.rootDir, m.filter))
+}
 
-		err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				// Log error but continue walking other paths
-				return nil
-			}
+// quit stops the running scan, if any, and exits
+func (m model) quit() (tea.Model, tea.Cmd) {
+	if m.cancelScan != nil {
+		m.cancelScan()
+	}
+	return m, tea.Quit
+}
 
-			if !info.IsDir() {
-				return nil
-			}
+// scanBatchInterval is how long the UI collects the projects of a running
+// scan before showing them, so a fast scan does not rebuild the tree for
+// every project
+const scanBatchInterval = 100 * time.Millisecond
+
+// scanStream carries the events of a running scan from the scanner's
+// goroutines to the UI
+type scanStream struct {
+	events  chan cacheremover.Event
+	err     error // Set before events is closed
+	scanner *cacheremover.Scanner
+}
 
-			// Skip deep nesting and hidden directories
-			depth := strings.Count(strings.TrimPrefix(path, rootDir), string(os.PathSeparator))
-			if depth > 10 || strings.Contains(path, "/.") {
-				return filepath.SkipDir
+// loadProjects starts scanning rootDir in the background and delivers the
+// first projects found. Each loadProgressMsg carries the stream to wait on
+// for the next ones, until a loadProjectsMsg ends the scan.
+func loadProjects(ctx context.Context, scanner *cacheremover.Scanner, rootDir string, filter projectFilter) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		stream := &scanStream{events: make(chan cacheremover.Event, 256), scanner: scanner}
+		scanner.OnEvent = func(event cacheremover.Event) {
+			switch event.Kind {
+			case cacheremover.EventProjectFound:
+			case cacheremover.EventProjectScanned:
+				// Scan returns the same project, so the filter works on a copy
+				if event.Project = filter.filtered(event.Project); event.Project == nil {
+					return
+				}
+			default:
+				return
 			}
+			select {
+			case stream.events <- event:
+			case <-ctx.Done():
+			}
+		}
 
-			// Skip descending into cache directories - they're meant to be removed as units
-			if isCacheDirectory(info.Name()) {
-				return filepath.SkipDir
+		go func() {
+			_, err := scanner.Scan(ctx, rootDir)
+			if err == nil {
+				// Failing to save only makes the next refresh slower
+				scanner.Index.Save()
 			}
+			stream.err = err
+			close(stream.events)
+		}()
+		return stream.next()
+	})
+}
 
-			if projectType := detectProjectType(path); projectType != nil {
-				cacheItems := findCacheItems(path, projectType.CacheConfig)
-				totalSize := int64(0)
-				for _, item := range cacheItems {
-					totalSize += item.Size
-				}
+// wait returns a command delivering the next projects of the scan
+func (s *scanStream) wait() tea.Cmd {
+	return func() tea.Msg {
+		return s.next()
+	}
+}
 
-				project := ProjectItem{
-					Project: &Project{
-						Name: filepath.Base(path),
-						Path: path,
-						Type: projectType.Name,
-					},
-					Selected:   false,
-					CacheItems: cacheItems,
-					TotalSize:  totalSize,
-					ItemCount:  len(cacheItems),
-				}
-				projects = append(projects, project)
+// next waits for the scan to report something, then collects whatever else
+// it reports within scanBatchInterval
+func (s *scanStream) next() tea.Msg {
+	msg := loadProgressMsg{stream: s}
+	var deadline <-chan time.Time
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				return loadProjectsMsg{projects: msg.projects, found: msg.found, err: s.err}
 			}
+			if event.Kind == cacheremover.EventProjectFound {
+				msg.found++
+			} else {
+				msg.projects = append(msg.projects, newProjectItem(s.scanner, event.Project))
+			}
+			if deadline == nil {
+				deadline = time.After(scanBatchInterval)
+			}
+		case <-deadline:
+			return msg
+		}
+	}
+}
 
-			return nil
-		})
+func newProjectItem(scanner *cacheremover.Scanner, p *cacheremover.Project) ProjectItem {
+	return ProjectItem{
+		Project: &Project{
+			Name: filepath.Base(p.Path),
+			Path: p.Path,
+			Type: p.Type.Name,
+		},
+		Source:     p,
+		Git:        scanner.RepoInfo(p.Path),
+		Selected:   false,
+		CacheItems: p.Items,
+		TotalSize:  p.TotalSize,
+		ItemCount:  len(p.Items),
+	}
+}
 
-		// Sort projects by cache size (largest first)
-		sort.Slice(projects, func(i, j int) bool {
-			return projects[i].TotalSize > projects[j].TotalSize
-		})
+// projectByPath returns the project at path, or nil if there is none
+func (m model) projectByPath(path string) *ProjectItem {
+	for i := range m.projects {
+		if m.projects[i].Project.Path == path {
+			return &m.projects[i]
+		}
+	}
+	return nil
+}
 
-		return loadProjectsMsg{projects: projects, err: err}
+// addProjects shows projects completed by the running scan. The projects
+// stay sorted by cache size (largest first), and the tree keeps its
+// expanded directories, cursor and selection.
+func (m *model) addProjects(projects []ProjectItem, found int) {
+	m.scanFound += found
+	m.loadingProgress = fmt.Sprintf("🔍 Scanning %s: %d projects found so far", m.rootDir, m.scanFound)
+	if len(projects) == 0 {
+		return
+	}
+
+	m.projects = append(m.projects, projects...)
+	sort.SliceStable(m.projects, func(i, j int) bool {
+		return m.projects[i].TotalSize > m.projects[j].TotalSize
 	})
+
+	// The scanned directory is the root, so the tree does not change shape
+	// as projects arrive
+	tree := buildProjectTree(m.projects, m.rootDir)
+	tree.restoreState(m.tree)
+	m.tree = tree
+
+	// Set up list view (for backward compatibility)
+	items := make([]list.Item, len(m.projects))
+	for i, project := range m.projects {
+		items[i] = project
+	}
+	m.list.SetItems(items)
+
+	totalSize := int64(0)
+	for _, p := range m.projects {
+		totalSize += p.TotalSize
+	}
+	m.list.Title = fmt.Sprintf("🧹 Cache Remover - %d Projects (%s potential cleanup)",
+		len(m.projects), formatBytes(totalSize))
 }
 
 // buildProjectTree creates a tree structure from a flat list of projects
@@ -419,6 +567,48 @@ func buildProjectTree(projects []ProjectItem, rootPath string) *TreeModel {
 	return treeModel
 }
 
+// restoreState carries the expanded directories and the cursor of old,
+// built from an earlier set of projects, over to the tree
+func (tm *TreeModel) restoreState(old *TreeModel) {
+	if old == nil {
+		return
+	}
+
+	expanded := make(map[string]bool)
+	var collect func(node *TreeNode)
+	collect = func(node *TreeNode) {
+		if !node.IsProject && node.Expanded {
+			expanded[node.Path] = true
+		}
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(old.Root)
+
+	var expand func(node *TreeNode)
+	expand = func(node *TreeNode) {
+		if !node.IsProject && node != tm.Root {
+			node.Expanded = expanded[node.Path]
+		}
+		for _, child := range node.Children {
+			expand(child)
+		}
+	}
+	expand(tm.Root)
+	tm.rebuildFlatView()
+
+	// A project and a directory holding nested projects share a path
+	if current := old.getCurrentNode(); current != nil {
+		for i, node := range tm.FlatView {
+			if node.Path == current.Path && node.IsProject == current.IsProject {
+				tm.CurrentIndex = i
+				break
+			}
+		}
+	}
+}
+
 // insertProjectIntoTree inserts a project into the appropriate position in the tree
 func insertProjectIntoTree(root *TreeNode, project *ProjectItem, rootPath string) {
 	// Get relative path from root
@@ -702,72 +892,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.progress.Width = msg.Width - 4
 
 	case loadProgressMsg:
-		m.loadingProgress = msg.progress
-		return m, nil
+		m.addProjects(msg.projects, msg.found)
+		// The tree can be browsed as soon as it has a project
+		if m.state == StateLoading && len(m.projects) > 0 {
+			m.state = StateProjectList
+		}
+		return m, msg.stream.wait()
 
 	case loadProjectsMsg:
 		m.loading = false
+		m.cancelScan()
+		m.cancelScan = nil
 		m.err = msg.err
-		m.projects = msg.projects
-
-		// Build tree structure from projects
-		if len(m.projects) > 0 {
-			// Use the common ancestor path as root, or current directory
-			rootPath := "."
-			if len(m.projects) > 0 {
-				rootPath = filepath.Dir(m.projects[0].Project.Path)
-				// Find common root for all projects
-				for _, project := range m.projects[1:] {
-					for !strings.HasPrefix(project.Project.Path, rootPath) {
-						rootPath = filepath.Dir(rootPath)
-						if rootPath == "/" || rootPath == "." {
-							break
-						}
-					}
-				}
-			}
-			m.tree = buildProjectTree(m.projects, rootPath)
+		m.addProjects(msg.projects, msg.found)
+		if m.state == StateLoading {
+			m.state = StateProjectList
 		}
 
-		// Set up list view (for backward compatibility)
-		items := make([]list.Item, len(m.projects))
-		for i, project := range m.projects {
-			items[i] = project
-		}
-
-		m.list.SetItems(items)
-		m.state = StateProjectList
-
-		totalSize := int64(0)
-		totalItems := 0
-		for _, p := range m.projects {
-			totalSize += p.TotalSize
-			totalItems += p.ItemCount
-		}
-
-		m.list.Title = fmt.Sprintf("🧹 Cache Remover - %d Projects (%s potential cleanup)",
-			len(m.projects), formatBytes(totalSize))
-
 	case cleanProgressMsg:
-		m.cleaningIndex = msg.index
-		m.cleaningProgress = msg.progress
-		m.cleaningResults = msg.results
-		m.currentProject = msg.currentProject
-		m.totalProjects = msg.totalProjects
-		if msg.completed {
-			m.projectsCompleted = msg.index
-		}
+		m.cleaning = msg
+		return m, msg.job.wait()
 
 	case cleanCompleteMsg:
+		m.cleanJob = nil
 		m.cleaningResults = msg.results
+		m.cleanOutcomes = msg.outcomes
 		m.state = StateResults
 
 	case tea.KeyMsg:
 		switch m.state {
+		case StateLoading:
+			if key.Matches(msg, m.keys.Quit) {
+				return m.quit()
+			}
+
 		case StateProjectList:
 			switch {
 			case key.Matches(msg, m.keys.Quit):
-				return m, tea.Quit
+				return m.quit()
 
 			case key.Matches(msg, m.keys.ToggleView):
 				// Toggle between tree and list view
@@ -853,30 +1015,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 			case key.Matches(msg, m.keys.Details):
-				var detailProject *ProjectItem
+				detailPath := ""
 
 				if m.useTreeView && m.tree != nil {
 					// Tree view: get current project
 					node := m.tree.getCurrentNode()
 					if node != nil && node.IsProject {
-						detailProject = node.Project
+						detailPath = node.Project.Project.Path
 					}
 				} else if selectedItem, ok := m.list.SelectedItem().(ProjectItem); ok {
 					// List view: get selected project
-					for i, project := range m.projects {
-						if project.Project.Path == selectedItem.Project.Path {
-							detailProject = &m.projects[i]
-							break
-						}
-					}
+					detailPath = selectedItem.Project.Path
 				}
 
-				if detailProject != nil {
-					m.detailsProject = detailProject
+				if detailPath != "" {
+					m.detailsPath = detailPath
 					m.state = StateDetails
 				}
 
-			case key.Matches(msg, m.keys.Clean):
+			// Nothing is cleaned while the scan may still be reading the projects
+			case key.Matches(msg, m.keys.Clean) && !m.loading:
 				var selectedProjects []ProjectItem
 
 				if m.useTreeView && m.tree != nil {
@@ -902,27 +1060,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.confirmMessage = fmt.Sprintf(
 						"Clean %d projects?\nThis will remove %d cache items (%s)\n\nPress 'y' to confirm, 'n' to cancel",
 						len(selectedProjects), totalItems, formatBytes(totalSize))
-					m.confirmAction = func() tea.Cmd {
-						// Initialize progress tracking
-						m.totalProjects = len(selectedProjects)
-						m.projectsCompleted = 0
-						m.currentProject = "Starting..."
-						m.cleaningProgress = 0.0
-						return cleanSelectedProjects(selectedProjects)
+					m.confirmAction = func(m *model) tea.Cmd {
+						return m.startClean(selectedProjects)
 					}
 					m.state = StateConfirm
 				}
 
-			case key.Matches(msg, m.keys.Refresh):
-				m.loading = true
-				m.state = StateLoading
-				return m, tea.Batch(m.spinner.Tick, loadProjects("."))
+			case key.Matches(msg, m.keys.Refresh) && !m.loading:
+				return m, m.startScan()
 			}
 
 		case StateDetails:
 			switch {
 			case key.Matches(msg, m.keys.Quit):
-				return m, tea.Quit
+				return m.quit()
 			case msg.String() == "esc":
 				m.state = StateProjectList
 			}
@@ -930,10 +1081,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case StateConfirm:
 			switch msg.String() {
 			case "y", "Y":
-				m.state = StateCleaning
-				m.cleaningIndex = 0
-				m.cleaningProgress = 0
-				return m, m.confirmAction()
+				return m, m.confirmAction(&m)
 			case "n", "N", "esc":
 				m.state = StateProjectList
 			}
@@ -941,7 +1089,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case StateCleaning:
 			switch {
 			case key.Matches(msg, m.keys.Quit):
-				return m, tea.Quit
+				// Quitting now would leave the current item half deleted.
+				// The clean stops after it and shows what was done.
+				if !m.cleanCancelled {
+					m.cleanCancelled = true
+					m.cleanJob.cancel()
+				}
 			}
 
 		case StateResults:
@@ -949,28 +1102,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case key.Matches(msg, m.keys.Quit):
 				return m, tea.Quit
 			case msg.String() == "esc", msg.String() == "enter":
-				m.state = StateProjectList
 				// Refresh the project list
-				m.loading = true
-				m.state = StateLoading
-				return m, tea.Batch(m.spinner.Tick, loadProjects("."))
+				return m, m.startScan()
 			}
 		}
 	}
 
-	// Update components
-	switch m.state {
-	case StateLoading:
+	// Update components. The spinner runs during a scan, which goes on
+	// while the projects found are browsed, and during cleaning.
+	if m.loading || m.state == StateCleaning {
 		m.spinner, cmd = m.spinner.Update(msg)
 		cmds = append(cmds, cmd)
+	}
+	switch m.state {
 	case StateProjectList:
 		m.list, cmd = m.list.Update(msg)
 		cmds = append(cmds, cmd)
 	case StateCleaning:
-		// Update both spinner and progress bar during cleaning
-		m.spinner, cmd = m.spinner.Update(msg)
-		cmds = append(cmds, cmd)
-
 		var progressModel tea.Model
 		progressModel, cmd = m.progress.Update(msg)
 		if pm, ok := progressModel.(progress.Model); ok {
@@ -982,26 +1130,185 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
-func cleanSelectedProjects(projects []ProjectItem) tea.Cmd {
-	return tea.Cmd(func() tea.Msg {
-		var wg sync.WaitGroup
-		results := CleanupStats{}
+// startClean cleans the given projects in the background
+func (m *model) startClean(projects []ProjectItem) tea.Cmd {
+	m.cleanJob = startCleanJob(m.cleaner, m.history, m.rootDir, projects, m.workers)
+	m.cleaning = cleanProgressMsg{job: m.cleanJob}
+	m.cleanCancelled = false
+	m.state = StateCleaning
+	return tea.Batch(m.spinner.Tick, m.cleanJob.wait())
+}
 
-		for _, project := range projects {
-			// Send progress update
-			tea.Printf("Cleaning %s...\n", project.Project.Name)
+// cleanJob cleans the selected projects in the background, several at a
+// time. Cancelling it lets the items being removed finish and leaves the
+// others alone.
+type cleanJob struct {
+	cancel   context.CancelFunc
+	start    time.Time
+	workers  int
+	projects int   // Number of projects selected
+	bytes    int64 // Cache size of the projects selected
+
+	mu       sync.Mutex
+	latest   cleanProgressMsg
+	owner    map[string]int // Project index of each cache item path
+	reported []int64        // Size of the items of each project reported done
+	updated  chan struct{}  // Signalled when latest changes
+	done     chan cleanCompleteMsg
+}
+
+// startCleanJob starts cleaning projects. Only one job may run at a time,
+// since it reports progress through the cleaner's OnProgress.
+func startCleanJob(cleaner *cacheremover.Cleaner, history *cacheremover.History, rootDir string, projects []ProjectItem, workers int) *cleanJob {
+	job, ctx := newCleanJob(projects, workers)
+	cleaner.OnProgress = job.itemProgress
+	go job.run(ctx, cleaner, history, rootDir, projects)
+	return job
+}
 
-			removedItems, removedSize := removeCacheItems(project.CacheItems, false)
-			results.TotalCacheItems += removedItems
-			results.TotalSizeRemoved += removedSize
-			results.TotalProjects++
+// newCleanJob prepares a job for projects, cancelled through ctx
+func newCleanJob(projects []ProjectItem, workers int) (*cleanJob, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &cleanJob{
+		cancel:   cancel,
+		start:    time.Now(),
+		workers:  workers,
+		projects: len(projects),
+		owner:    make(map[string]int),
+		reported: make([]int64, len(projects)),
+		updated:  make(chan struct{}, 1),
+		done:     make(chan cleanCompleteMsg, 1),
+	}
+	for i, project := range projects {
+		job.bytes += project.TotalSize
+		for _, item := range project.CacheItems {
+			job.owner[item.Path] = i
 		}
+	}
+	return job, ctx
+}
 
-		wg.Wait()
-		return cleanCompleteMsg{results: results}
+func (j *cleanJob) run(ctx context.Context, cleaner *cacheremover.Cleaner, history *cacheremover.History, rootDir string, projects []ProjectItem) {
+	defer j.cancel()
+	results := &cacheremover.CleanupStats{}
+	outcomes := make([]cleanOutcome, len(projects))
+	for i, project := range projects {
+		outcomes[i].project = project
+	}
+
+	runWorkers(ctx, j.workers, len(projects), func(i int) {
+		project := projects[i]
+		j.update(func(p *cleanProgressMsg) {
+			p.cleaning = append(p.cleaning, project.Project.Name)
+		})
+
+		result, _ := cleaner.CleanProject(ctx, project.Source)
+		outcomes[i].result = result
+		results.Add(len(result.Removed), result.BytesRemoved, result.ApparentBytesRemoved)
+		results.IncrementProjects()
+
+		j.update(func(p *cleanProgressMsg) {
+			p.cleaning = without(p.cleaning, project.Project.Name)
+			p.projectsDone++
+			// Items a failed clean command stood in for were never
+			// reported; skipped items were not dealt with
+			unreported := project.TotalSize - j.reported[i]
+			for _, item := range result.Skipped {
+				unreported -= item.Size
+			}
+			p.bytesDone += max(unreported, 0)
+		})
+	})
+
+	// Record the projects in the order they were selected
+	record := cacheremover.NewHistoryRecord(rootDir, "tui")
+	for _, outcome := range outcomes {
+		if outcome.result != nil {
+			record.AddProject(outcome.project.Project.Path, outcome.project.Project.Type, outcome.result)
+		}
+	}
+	results.ProcessingTime = time.Since(j.start)
+	if len(record.Projects) > 0 {
+		record.Duration = results.ProcessingTime
+		// History is best effort; a failure to write it must not hide the results
+		history.Append(record)
+	}
+	j.done <- cleanCompleteMsg{results: results, outcomes: outcomes}
+}
+
+// itemProgress follows the cache items as the cleaner removes them. It is
+// called from the goroutine of each project being cleaned.
+func (j *cleanJob) itemProgress(progress cacheremover.CleanProgress) {
+	j.update(func(p *cleanProgressMsg) {
+		path := progress.Item.Path
+		if !progress.Done {
+			p.removing = append(p.removing, path)
+			return
+		}
+		p.removing = without(p.removing, path)
+		if i, ok := j.owner[path]; ok {
+			j.reported[i] += progress.Item.Size
+		}
+		p.bytesDone += progress.Item.Size
+		p.bytesFreed += progress.BytesFreed
+		if progress.Err == nil {
+			p.itemsRemoved++
+		}
 	})
 }
 
+// without returns list without the first occurrence of s
+func without(list []string, s string) []string {
+	for i, v := range list {
+		if v == s {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// update changes the latest progress. The UI only shows the latest, so
+// updates it has not picked up yet are simply replaced.
+func (j *cleanJob) update(change func(p *cleanProgressMsg)) {
+	j.mu.Lock()
+	change(&j.latest)
+	j.latest.job = j
+	j.mu.Unlock()
+
+	select {
+	case j.updated <- struct{}{}:
+	default:
+	}
+}
+
+// wait returns a command delivering the next progress of the job, or its
+// outcome once it is over
+func (j *cleanJob) wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-j.updated:
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			// The job goes on changing its lists
+			latest := j.latest
+			latest.cleaning = append([]string(nil), latest.cleaning...)
+			latest.removing = append([]string(nil), latest.removing...)
+			return latest
+		case msg := <-j.done:
+			return msg
+		}
+	}
+}
+
+// eta estimates how long the clean has left from the bytes dealt with so far
+func (j *cleanJob) eta(bytesDone int64) (time.Duration, bool) {
+	if bytesDone <= 0 || bytesDone >= j.bytes {
+		return 0, false
+	}
+	elapsed := time.Since(j.start)
+	return time.Duration(float64(elapsed) * float64(j.bytes-bytesDone) / float64(bytesDone)), true
+}
+
 func (m model) View() string {
 	switch m.state {
 	case StateLoading:
@@ -1022,35 +1329,62 @@ func (m model) View() string {
 			}
 
 			statusBar := ""
+			if m.loading {
+				statusBar = m.renderScanStatus() + "\n"
+			}
 			if selectedCount > 0 {
-				statusBar = warningStyle.Render(fmt.Sprintf(
+				statusBar += warningStyle.Render(fmt.Sprintf(
 					" Selected: %d projects (%s) - Press 'c' to clean ",
 					selectedCount, formatBytes(selectedSize)))
 			} else {
-				statusBar = helpStyle.Render(" Use ↑/↓ to navigate, Space to select, 'c' to clean, 't' to toggle tree view ")
+				statusBar += helpStyle.Render(" Use ↑/↓ to navigate, Space to select, 'c' to clean, 't' to toggle tree view ")
 			}
 
 			return m.list.View() + "\n" + statusBar
 		}
 
 	case StateDetails:
-		if m.detailsProject == nil {
+		detailsProject := m.projectByPath(m.detailsPath)
+		if detailsProject == nil {
 			return "No project selected"
 		}
 
-		details := fmt.Sprintf("📁 %s (%s)\n", m.detailsProject.Project.Name, m.detailsProject.Project.Type)
-		details += fmt.Sprintf("Path: %s\n\n", m.detailsProject.Project.Path)
-		details += fmt.Sprintf("Cache Items (%d):\n", len(m.detailsProject.CacheItems))
+		details := fmt.Sprintf("📁 %s (%s)\n", detailsProject.Project.Name, detailsProject.Project.Type)
+		details += fmt.Sprintf("Path: %s\n", detailsProject.Project.Path)
+		if source := detailsProject.Source; source != nil && !source.LastActivity.IsZero() {
+			details += fmt.Sprintf("Last activity: %s ago (%s)\n",
+				formatAge(time.Since(source.LastActivity)), source.LastActivity.Format("2006-01-02"))
+		}
+		if git := detailsProject.Git; git != nil {
+			details += fmt.Sprintf("Git: %s\n", describeGit(git))
+		}
+		if source := detailsProject.Source; source != nil && len(source.Members) > 0 {
+			details += fmt.Sprintf("Workspace members (%d):\n", len(source.Members))
+			for _, member := range source.Members {
+				rel, _ := filepath.Rel(source.Path, member.Path)
+				details += fmt.Sprintf("  📦 %s (%s) - %s\n", filepath.ToSlash(rel), member.Type.Name, formatBytes(member.TotalSize))
+			}
+		}
+		details += "\n"
+		details += fmt.Sprintf("Cache Items (%d):\n", len(detailsProject.CacheItems))
 
-		for _, item := range m.detailsProject.CacheItems {
+		for _, item := range detailsProject.CacheItems {
 			itemType := "📄"
-			if item.Type == "directory" {
+			switch item.Type {
+			case "directory":
 				itemType = "📁"
+			case "symlink":
+				itemType = "🔗"
+			}
+			name := filepath.Base(item.Path)
+			if rel, err := filepath.Rel(detailsProject.Project.Path, item.Path); err == nil {
+				// Items of workspace members are nested below the project
+				name = rel
 			}
-			details += fmt.Sprintf("  %s %s (%s)\n", itemType, filepath.Base(item.Path), formatBytes(item.Size))
+			details += fmt.Sprintf("  %s %s (%s)%s\n", itemType, name, formatBytes(item.Size), gitStatusSuffix(item))
 		}
 
-		details += fmt.Sprintf("\nTotal Size: %s\n", formatBytes(m.detailsProject.TotalSize))
+		details += fmt.Sprintf("\nTotal Size: %s\n", formatBytes(detailsProject.TotalSize))
 		details += helpStyle.Render("\nPress ESC to go back")
 
 		return details
@@ -1059,86 +1393,153 @@ func (m model) View() string {
 		return fmt.Sprintf("\n%s\n", warningStyle.Render(m.confirmMessage))
 
 	case StateCleaning:
-		// Create animated spinner for active cleanup
-		spinnerView := m.spinner.View()
+		return m.renderCleaningView()
 
-		// Status message
-		status := fmt.Sprintf("%s Cleaning cache files...", spinnerView)
-		if m.currentProject != "" && m.currentProject != "Starting..." {
-			status = fmt.Sprintf("%s Cleaning: %s", spinnerView, m.currentProject)
-		}
+	case StateResults:
+		return m.renderResultsView()
+	}
 
-		// Progress information
-		progressInfo := "Initializing cleanup..."
-		if m.totalProjects > 0 {
-			progressInfo = fmt.Sprintf("Project %d of %d", m.projectsCompleted+1, m.totalProjects)
-		}
+	return ""
+}
 
-		// Progress bar - always show some progress during cleanup
-		displayProgress := m.cleaningProgress
-		if displayProgress == 0.0 && m.totalProjects > 0 {
-			// Show some progress even when starting
-			displayProgress = 0.1
-		}
-		progressBar := m.progress.ViewAs(displayProgress)
-
-		// Current statistics
-		stats := fmt.Sprintf(
-			"📊 Status: Processing cache directories\n"+
-				"🗑️  Items removed: %d\n"+
-				"💾 Space reclaimed: %s",
-			m.cleaningResults.TotalCacheItems,
-			formatBytes(m.cleaningResults.TotalSizeRemoved))
-
-		return fmt.Sprintf("\n\n   %s\n   %s\n\n   %s\n\n   %.1f%% Complete\n\n%s\n\n   💡 Tip: This may take a moment for large cache directories\n   Press 'q' to cancel\n\n",
-			status,
-			progressInfo,
-			progressBar,
-			displayProgress*100,
-			stats)
+// renderCleaningView shows the progress of the running clean
+func (m model) renderCleaningView() string {
+	p := m.cleaning
+	job := p.job
 
-	case StateResults:
-		results := statsStyle.Render(fmt.Sprintf(
-			"✅ Cleanup Complete!\n\n"+
-				"Projects cleaned: %d\n"+
-				"Cache items removed: %d\n"+
-				"Space reclaimed: %s\n\n"+
-				"Press ENTER to continue",
-			m.cleaningResults.TotalProjects,
-			m.cleaningResults.TotalCacheItems,
-			formatBytes(m.cleaningResults.TotalSizeRemoved)))
+	status := fmt.Sprintf("%s Cleaning cache files...", m.spinner.View())
+	if m.cleanCancelled {
+		status = fmt.Sprintf("%s Stopping after the current items...", m.spinner.View())
+	} else if len(p.cleaning) > 0 {
+		status = fmt.Sprintf("%s Cleaning: %s", m.spinner.View(), truncateString(strings.Join(p.cleaning, ", "), max(m.width-16, 20)))
+	}
+	projectInfo := fmt.Sprintf("Projects done: %d of %d", p.projectsDone, job.projects)
 
-		return fmt.Sprintf("\n%s\n", results)
+	// One line per item being removed, at most one per worker
+	var removing []string
+	for _, path := range p.removing {
+		removing = append(removing, "🗑️  "+truncateString(path, max(m.width-10, 20)))
+	}
+	current := strings.Join(removing, "\n   ")
+
+	// Progress follows the bytes dealt with, which is what the ETA is based on
+	fraction := 0.0
+	if job.bytes > 0 {
+		fraction = float64(p.bytesDone) / float64(job.bytes)
+	} else if job.projects > 0 {
+		fraction = float64(p.projectsDone) / float64(job.projects)
+	}
+	timing := fmt.Sprintf("⏱️  %s elapsed", time.Since(job.start).Round(time.Second))
+	if eta, ok := job.eta(p.bytesDone); ok {
+		timing += fmt.Sprintf(", about %s left", eta.Round(time.Second))
 	}
 
-	return ""
+	stats := fmt.Sprintf(
+		"🗑️  Items removed: %d\n"+
+			"   💾 Space reclaimed: %s (%s of %s done)",
+		p.itemsRemoved, formatBytes(p.bytesFreed), formatBytes(p.bytesDone), formatBytes(job.bytes))
+
+	help := "Press 'q' to stop after the items being removed"
+	if m.cleanCancelled {
+		help = "Items not yet started will be left in place"
+	}
+
+	return fmt.Sprintf("\n\n   %s\n   %s\n   %s\n\n   %s\n\n   %.1f%% Complete - %s\n\n   %s\n\n   %s\n\n",
+		status,
+		projectInfo,
+		current,
+		m.progress.ViewAs(fraction),
+		fraction*100,
+		timing,
+		stats,
+		helpStyle.Render(help))
+}
+
+// renderResultsView reports what the last clean removed and, item by item,
+// what it failed to remove or left in place because it was cancelled
+func (m model) renderResultsView() string {
+	var completed, failed, notRemoved int
+	var lines []string
+	for _, outcome := range m.cleanOutcomes {
+		project := outcome.project
+		result := outcome.result
+		if result == nil {
+			notRemoved += len(project.CacheItems)
+			lines = append(lines, warningStyle.Render(fmt.Sprintf("⏭️  %s: not started, %d items (%s) left in place",
+				project.Project.Path, len(project.CacheItems), formatBytes(project.TotalSize))))
+			continue
+		}
+
+		if len(result.Skipped) == 0 {
+			completed++
+		}
+		failed += len(result.Failed)
+		notRemoved += len(result.Skipped)
+		lines = append(lines, successStyle.Render(fmt.Sprintf("✅ %s: removed %d of %d items (%s)",
+			project.Project.Path, len(result.Removed), len(project.CacheItems), formatBytes(result.BytesRemoved))))
+		for _, failure := range result.Failed {
+			lines = append(lines, errorStyle.Render(fmt.Sprintf("   ❌ %s: %v", failure.Item.Path, failure.Err)))
+		}
+		for _, item := range result.Skipped {
+			lines = append(lines, warningStyle.Render(fmt.Sprintf("   ⏭️  %s (%s): left in place", item.Path, formatBytes(item.Size))))
+		}
+	}
+
+	title := "✅ Cleanup Complete!"
+	switch {
+	case notRemoved > 0:
+		title = "⏹️  Cleanup Cancelled"
+	case failed > 0:
+		title = "⚠️  Cleanup Finished With Errors"
+	}
+	results := statsStyle.Render(fmt.Sprintf(
+		"%s\n\n"+
+			"Projects cleaned: %d of %d\n"+
+			"Cache items removed: %d\n"+
+			"Failed: %d\n"+
+			"Not removed: %d\n"+
+			"Space reclaimed: %s\n\n"+
+			"Press ENTER to continue",
+		title,
+		completed, len(m.cleanOutcomes),
+		m.cleaningResults.TotalCacheItems,
+		failed,
+		notRemoved,
+		formatBytes(m.cleaningResults.TotalSizeRemoved)))
+
+	// The history keeps every item; show what fits
+	if room := m.height - lipgloss.Height(results) - 4; room > 0 && len(lines) > room {
+		hidden := len(lines) - room + 1
+		lines = append(lines[:room-1], helpStyle.Render(fmt.Sprintf("... and %d more lines, see 'cache-remover history'", hidden)))
+	}
+	return fmt.Sprintf("\n%s\n%s\n", results, strings.Join(lines, "\n"))
 }
 
 // renderLoadingView renders an enhanced loading screen with progress information
 func (m model) renderLoadingView() string {
 	var output strings.Builder
-	
+
 	// Title
 	title := titleStyle.Render("🧹 Cache Remover - Scanning Projects")
 	output.WriteString(title + "\n\n")
-	
+
 	// Loading status with spinner
 	loadingText := "🔍 Scanning directory: " + m.rootDir
 	if m.loadingProgress != "" {
 		loadingText = m.loadingProgress
 	}
-	
+
 	loadingLine := fmt.Sprintf("   %s %s", m.spinner.View(), loadingStyle.Render(loadingText))
 	output.WriteString(loadingLine + "\n\n")
-	
+
 	// Status information
 	statusInfo := statusBarStyle.Render(" Please wait while we discover your projects... ")
 	output.WriteString(statusInfo + "\n\n")
-	
+
 	// Help text
 	helpText := helpStyle.Render("Press 'q' to quit")
 	output.WriteString(helpText)
-	
+
 	return output.String()
 }
 
@@ -1161,18 +1562,18 @@ func (m model) renderTreeView() string {
 	// Title with tree icon
 	title := titleStyle.Render("🌳 Cache Remover - Tree View")
 	output.WriteString(title + "\n\n")
-	
+
 	// Add column headers
 	colWidths := m.calculateColumnWidths()
 	headerLine := fmt.Sprintf("%-*s", colWidths.name, "            Name")
-	
+
 	if colWidths.gitBranch > 0 {
 		headerLine += fmt.Sprintf(" | %-*s", colWidths.gitBranch, "Git Branch")
 	}
-	
+
 	headerLine += fmt.Sprintf(" | %*s", colWidths.size, "Size")
 	output.WriteString(helpStyle.Render(headerLine) + "\n")
-	
+
 	// Add separator line
 	separatorLine := strings.Repeat("─", colWidths.name)
 	if colWidths.gitBranch > 0 {
@@ -1222,30 +1623,38 @@ func (m model) renderTreeView() string {
 // renderEnhancedStatusBar creates a comprehensive status bar with project statistics
 func (m model) renderEnhancedStatusBar(selectedProjects []ProjectItem, selectedSize int64) string {
 	var statusLines []string
-	
+
 	// Calculate total statistics
 	totalProjects := len(m.projects)
 	var totalCacheSize int64
 	var projectsWithCache int
-	
+
 	for _, project := range m.projects {
 		if project.TotalSize > 0 {
 			totalCacheSize += project.TotalSize
 			projectsWithCache++
 		}
 	}
-	
+
 	// Main statistics line
-	statsLine := fmt.Sprintf("📊 Projects: %d | With Cache: %d | Total Cache: %s", 
+	statsLine := fmt.Sprintf("📊 Projects: %d | With Cache: %d | Total Cache: %s",
 		totalProjects, projectsWithCache, formatBytes(totalCacheSize))
-	
+
+	if m.loading {
+		statusLines = append(statusLines, m.renderScanStatus())
+	}
+
 	if len(selectedProjects) > 0 {
 		// Selection statistics
-		selectionLine := fmt.Sprintf("🎯 Selected: %d projects | Will Reclaim: %s", 
+		selectionLine := fmt.Sprintf("🎯 Selected: %d projects | Will Reclaim: %s",
 			len(selectedProjects), formatBytes(selectedSize))
 		statusLines = append(statusLines, warningStyle.Render(" "+selectionLine+" "))
 		statusLines = append(statusLines, infoStyle.Render(" "+statsLine+" "))
-		statusLines = append(statusLines, helpStyle.Render(" Press 'c' to clean selected, Space to select/deselect, 'q' to quit "))
+		if m.loading {
+			statusLines = append(statusLines, helpStyle.Render(" Cleaning is available once the scan finishes, Space to select/deselect, 'q' to quit "))
+		} else {
+			statusLines = append(statusLines, helpStyle.Render(" Press 'c' to clean selected, Space to select/deselect, 'q' to quit "))
+		}
 	} else {
 		// No selection - show discovery info and help
 		statusLines = append(statusLines, infoStyle.Render(" "+statsLine+" "))
@@ -1255,15 +1664,25 @@ func (m model) renderEnhancedStatusBar(selectedProjects []ProjectItem, selectedS
 			statusLines = append(statusLines, helpStyle.Render(" ↑/↓:navigate ←/→:expand/collapse Space:select 'c':clean 't':list view "))
 		}
 	}
-	
+
 	return strings.Join(statusLines, "\n")
 }
 
+// renderScanStatus renders the progress of a scan that is still running
+func (m model) renderScanStatus() string {
+	var totalCacheSize int64
+	for _, project := range m.projects {
+		totalCacheSize += project.TotalSize
+	}
+	return loadingStyle.Render(fmt.Sprintf(" %s Scanning: %d projects / %s found so far ",
+		m.spinner.View(), len(m.projects), formatBytes(totalCacheSize)))
+}
+
 // Column width configuration for responsive layout
 type columnWidths struct {
-	name     int
-	gitBranch int  
-	size     int
+	name      int
+	gitBranch int
+	size      int
 }
 
 // calculateColumnWidths determines optimal column widths based on terminal width
@@ -1272,23 +1691,23 @@ func (m model) calculateColumnWidths() columnWidths {
 	if totalWidth < 70 {
 		// Narrow terminal - minimal layout
 		return columnWidths{
-			name:     totalWidth - 25,
+			name:      totalWidth - 25,
 			gitBranch: 0, // Hide git branch column
-			size:     15,
+			size:      15,
 		}
 	} else if totalWidth < 110 {
-		// Medium terminal 
+		// Medium terminal
 		return columnWidths{
-			name:     totalWidth - 45,
+			name:      totalWidth - 45,
 			gitBranch: 15,
-			size:     20,
+			size:      20,
 		}
 	} else {
 		// Wide terminal - full layout with enhanced size column
 		return columnWidths{
-			name:     totalWidth - 55,
+			name:      totalWidth - 55,
 			gitBranch: 20,
-			size:     25,
+			size:      25,
 		}
 	}
 }
@@ -1298,7 +1717,7 @@ func getTreeStructureSymbol(node *TreeNode, isLast bool) string {
 	if node.Level == 0 {
 		return ""
 	}
-	
+
 	if isLast {
 		return "└── "
 	} else {
@@ -1311,7 +1730,7 @@ func isLastChild(node *TreeNode) bool {
 	if node.Parent == nil {
 		return true
 	}
-	
+
 	// Find this node in parent's children
 	for i, child := range node.Parent.Children {
 		if child == node {
@@ -1324,8 +1743,9 @@ func isLastChild(node *TreeNode) bool {
 // getFileTypeIcon returns appropriate icon based on file type and project type
 func getFileTypeIcon(node *TreeNode) string {
 	if node.IsProject && node.Project != nil {
-		// Project type icons
-		switch strings.ToLower(node.Project.Project.Type) {
+		// Project type icons; combined types such as "Node.js+Python" use the first
+		primary, _, _ := strings.Cut(node.Project.Project.Type, "+")
+		switch strings.ToLower(primary) {
 		case "node.js", "nodejs":
 			return "📦"
 		case "python":
@@ -1398,27 +1818,27 @@ func getSelectionIcon(node *TreeNode, m model) string {
 }
 
 // populateNodeMetadata calculates and populates file size and count for a node
-func populateNodeMetadata(node *TreeNode) {
+func populateNodeMetadata(node *TreeNode, scanner *cacheremover.Scanner) {
 	if node.FileSize > 0 || node.Path == "" {
 		return // Already populated or invalid path
 	}
-	
+
 	stat, err := os.Stat(node.Path)
 	if err != nil {
 		return // Can't access file/directory
 	}
-	
+
 	node.LastModified = stat.ModTime()
-	
+
 	if stat.IsDir() {
 		node.IsFile = false
 		node.FileType = "directory"
 		if node.IsProject {
 			node.FileType = "project"
 		}
-		
+
 		// Calculate directory size and file count (with limits for performance)
-		size, count := calculateDirSizeAndCount(node.Path, 1000) // Limit to 1000 files for performance
+		size, count := calculateDirSizeAndCount(scanner, node.Path, 1000) // Limit to 1000 files for performance
 		node.FileSize = size
 		node.FileCount = count
 	} else {
@@ -1430,35 +1850,35 @@ func populateNodeMetadata(node *TreeNode) {
 }
 
 // calculateDirSizeAndCount calculates directory size and file count with limits and optimizations
-func calculateDirSizeAndCount(dirPath string, maxFiles int) (int64, int) {
+func calculateDirSizeAndCount(scanner *cacheremover.Scanner, dirPath string, maxFiles int) (int64, int) {
 	var totalSize int64
 	var fileCount int
-	
+
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip inaccessible files
 		}
-		
+
 		// Skip cache directories to avoid performance issues (same optimization as main scanning)
-		if info.IsDir() && isCacheDirectory(info.Name()) {
+		if info.IsDir() && scanner.IsCacheDirectory(info.Name()) {
 			return filepath.SkipDir
 		}
-		
+
 		fileCount++
 		if fileCount > maxFiles {
 			return filepath.SkipDir // Stop if too many files for performance
 		}
-		
+
 		if !info.IsDir() {
 			totalSize += info.Size()
 		}
 		return nil
 	})
-	
+
 	if err != nil {
 		return 0, -1 // Error calculating
 	}
-	
+
 	return totalSize, fileCount
 }
 
@@ -1473,19 +1893,66 @@ func truncateString(text string, maxWidth int) string {
 	return text[:maxWidth-3] + "..."
 }
 
+// describeGit is the long form of formatGitColumn used in the details view
+func describeGit(info *cacheremover.RepoInfo) string {
+	text := "on branch " + info.Branch
+	if info.Detached {
+		text = "detached HEAD at " + info.Commit
+	}
+
+	if info.Dirty {
+		text += ", modified files"
+	} else {
+		text += ", clean"
+	}
+	if !info.LastCommit.IsZero() {
+		text += fmt.Sprintf(", last commit %s ago (%s)",
+			formatAge(time.Since(info.LastCommit)), info.LastCommit.Format("2006-01-02"))
+	}
+	return text + " - " + info.Root
+}
+
+// formatGitColumn describes a checkout as "branch ✓ 3mo": the branch (or
+// @commit when detached), "✓" when clean or "*" when modified, and the age
+// of the last commit. The branch is shortened to keep the whole text within width.
+func formatGitColumn(info *cacheremover.RepoInfo, width int) string {
+	if info == nil {
+		return "-"
+	}
+
+	branch := info.Branch
+	if info.Detached && len(info.Commit) >= 7 {
+		branch = "@" + info.Commit[:7]
+	}
+
+	suffix := " ✓"
+	if info.Dirty {
+		suffix = " *"
+	}
+	if !info.LastCommit.IsZero() {
+		suffix += " " + formatAge(time.Since(info.LastCommit))
+	}
+
+	suffixWidth := len([]rune(suffix))
+	if len(branch)+suffixWidth > width && width > suffixWidth {
+		branch = truncateString(branch, width-suffixWidth)
+	}
+	return branch + suffix
+}
+
 // renderTreeNode renders a single tree node with column-based layout
 func (m model) renderTreeNode(node *TreeNode, isSelected bool) string {
 	// Populate metadata if not already done
-	populateNodeMetadata(node)
-	
+	populateNodeMetadata(node, m.scanner)
+
 	// Calculate column widths
 	colWidths := m.calculateColumnWidths()
-	
+
 	var output strings.Builder
-	
+
 	// Build name column with tree structure
 	var nameColumn strings.Builder
-	
+
 	// Add tree structure indentation with proper box-drawing characters
 	var indent string
 	if node.Level > 0 {
@@ -1494,27 +1961,30 @@ func (m model) renderTreeNode(node *TreeNode, isSelected bool) string {
 		isLast := isLastChild(node)
 		indent += getTreeStructureSymbol(node, isLast)
 	}
-	
+
 	// Add selection and file type icons
 	selectionIcon := getSelectionIcon(node, m)
 	fileIcon := getFileTypeIcon(node)
-	
+
 	nameColumn.WriteString(indent)
 	nameColumn.WriteString(selectionIcon)
 	nameColumn.WriteString(" ")
 	nameColumn.WriteString(fileIcon)
 	nameColumn.WriteString("  ")
 	nameColumn.WriteString(node.Name)
-	
+	if node.IsProject && node.Project != nil && node.Project.Source != nil && !node.Project.Source.LastActivity.IsZero() {
+		nameColumn.WriteString(fmt.Sprintf(" (idle %s)", formatAge(time.Since(node.Project.Source.LastActivity))))
+	}
+
 	// Truncate name column if too long
 	nameText := truncateString(nameColumn.String(), colWidths.name)
-	
-	// Build git branch column (placeholder for now - Phase 2)
+
+	// Build git branch column
 	gitBranchText := ""
-	if colWidths.gitBranch > 0 {
-		gitBranchText = "" // Empty for Phase 1, will be populated in Phase 2
+	if colWidths.gitBranch > 0 && node.IsProject && node.Project != nil {
+		gitBranchText = formatGitColumn(node.Project.Git, colWidths.gitBranch)
 	}
-	
+
 	// Build size column with enhanced information
 	sizeText := ""
 	if node.IsProject && node.Project != nil {
@@ -1538,16 +2008,16 @@ func (m model) renderTreeNode(node *TreeNode, isSelected bool) string {
 	} else {
 		sizeText = "-"
 	}
-	
+
 	// Assemble the full line with proper spacing
 	line := fmt.Sprintf("%-*s", colWidths.name, nameText)
-	
+
 	if colWidths.gitBranch > 0 {
 		line += fmt.Sprintf(" | %-*s", colWidths.gitBranch, gitBranchText)
 	}
-	
+
 	line += fmt.Sprintf(" | %*s", colWidths.size, sizeText)
-	
+
 	// Apply styling based on selection and node type
 	if isSelected {
 		// Apply selected styling without adding prefix (to maintain column alignment)
@@ -1558,9 +2028,9 @@ func (m model) renderTreeNode(node *TreeNode, isSelected bool) string {
 	} else {
 		line = itemStyle.Render(line)
 	}
-	
+
 	output.WriteString(line)
-	
+
 	return output.String()
 }
 
@@ -1588,8 +2058,8 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	fmt.Fprint(w, "\n"+itemStyle.Render(i.Description()))
 }
 
-func runInteractiveUI(rootDir string) error {
-	p := tea.NewProgram(initialModel(rootDir), tea.WithAltScreen())
+func runInteractiveUI(rootDir string, scanner *cacheremover.Scanner, cleaner *cacheremover.Cleaner, history *cacheremover.History, filter projectFilter, workers int) error {
+	p := tea.NewProgram(initialModel(rootDir, scanner, cleaner, history, filter, workers), tea.WithAltScreen())
 	_, err := p.Run()
 	return err
 }
@@ -2,76 +2,87 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"os/signal"
 	"strings"
 	"sync"
 	"time"
-)
-
-type CacheConfig struct {
-	Directories []string `json:"directories"`
-	Files       []string `json:"files"`
-	Extensions  []string `json:"extensions"`
-}
-
-type ProjectType struct {
-	Name        string      `json:"name"`
-	Indicators  []string    `json:"indicators"`
-	CacheConfig CacheConfig `json:"cache_config"`
-}
-
-type CacheItem struct {
-	Path string
-	Size int64
-	Type string
-}
 
-type CleanupStats struct {
-	TotalProjects    int
-	TotalCacheItems  int
-	TotalSizeRemoved int64
-	ProcessingTime   time.Duration
-	mu               sync.Mutex
-}
-
-func (s *CleanupStats) Add(items int, size int64) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.TotalCacheItems += items
-	s.TotalSizeRemoved += size
-}
+	"cache-remover-utility/cacheremover"
+)
 
-func (s *CleanupStats) IncrementProjects() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.TotalProjects++
+// cleanOptions holds the per-run settings shared by the CLI workers
+type cleanOptions struct {
+	workers     int
+	dryRun      bool
+	verbose     bool
+	interactive bool
+	trash       bool
+	filter      projectFilter
+	targetFree  int64            // Stop once this many bytes are reclaimed; 0 cleans everything
+	freeSpace   *freeSpaceReport // Set by --when-free-below
 }
 
 func main() {
 	// Load configuration first
-	config, err := loadConfig()
+	config, configPath, err := loadConfig()
 	if err != nil {
-		fmt.Printf("Error loading configuration: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Subcommands such as `restore`, `purge`, `history` and `tag` have their own flags
+	if handled, err := runCommand(config, os.Args[1:]); handled {
+		exitOnCommandError(err)
+		return
+	}
+
 	var (
 		rootDir     = flag.String("dir", ".", "Root directory to scan for projects")
 		dryRun      = flag.Bool("dry-run", false, "Show what would be removed without actually removing")
-		workers     = flag.Int("workers", config.Settings.DefaultWorkers, "Number of worker goroutines")
+		workers     = flag.Int("workers", config.Settings.DefaultWorkers, "Number of projects cleaned in parallel")
 		verbose     = flag.Bool("verbose", false, "Verbose output")
 		maxDepth    = flag.Int("max-depth", config.Settings.MaxDepth, "Maximum directory depth to scan")
 		interactive = flag.Bool("interactive", false, "Ask for confirmation before removing each cache")
 		ui          = flag.Bool("ui", false, "Launch interactive TUI mode")
 		saveConfig  = flag.Bool("save-config", false, "Save default configuration to current directory")
 		listTypes   = flag.Bool("list-types", false, "List all supported project types")
+		output      = flag.String("output", outputText, "Output format: text, json or ndjson")
+		trash       = flag.Bool("trash", false, "Move cache items to the trash instead of deleting them (undo with 'restore')")
+		tracked     = flag.Bool("include-tracked", false, "Also remove cache items that contain files tracked by git")
+		olderThan   = flag.String("older-than", "", "Only clean projects with no activity for this long (e.g. 30d, 2w)")
+		gitActivity = flag.Bool("git-activity", false, "Count the last git commit as project activity for --older-than")
+		minSize     = flag.String("min-size", "", "Ignore cache items smaller than this (e.g. 10M)")
+		minProject  = flag.String("min-project-size", "", "Ignore projects whose cache totals less than this (e.g. 100M)")
+		targetFree  = flag.String("target-free", "", "Clean the best candidates until this much is reclaimed (e.g. 20G)")
+		freeBelow   = flag.String("when-free-below", "", "Only clean when free space on the filesystem of --dir is below this (e.g. 10% or 50G)")
+		freeUntil   = flag.String("free-until", "", "With --when-free-below, clean until free space reaches this (default: twice the threshold)")
+		oneFS       = flag.Bool("one-file-system", true, "Don't descend into directories on other filesystems or bind mounts while looking for projects")
+		noIndex     = flag.Bool("no-index", false, "Read every cache directory again instead of reusing the sizes of unchanged ones from the scan index")
+		global      = flag.Bool("global", false, "Size and prune toolchain caches in your home directory (~/.m2, ~/.npm, ...) instead of projects")
+		allowCmds   = flag.Bool("allow-commands", false, "Run the clean commands of a configuration file in the current directory")
 	)
 	flag.Parse()
 
+	out, err := newReporter(*output, os.Stdout, *verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if *interactive && *output != outputText {
+		fmt.Fprintf(os.Stderr, "Error: --interactive requires --output text\n")
+		os.Exit(2)
+	}
+
+	// Keep stdout clean for machine-readable output
+	if *output == outputText {
+		printConfigSource(configPath)
+	}
+
 	// Handle special flags
 	if *saveConfig {
 		if err := saveDefaultConfig(); err != nil {
@@ -87,573 +98,293 @@ func main() {
 		return
 	}
 
+	var filter projectFilter
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --older-than: %v\n", err)
+			os.Exit(2)
+		}
+		filter.olderThan = age
+	}
+	filter.minItemSize = sizeFlag("min-size", *minSize)
+	filter.minProjectSize = sizeFlag("min-project-size", *minProject)
+
 	// Handle positional argument for directory
 	if len(flag.Args()) > 0 {
 		*rootDir = flag.Args()[0]
 	}
 
+	if !*allowCmds && !trustedConfig(configPath) {
+		if dropped := dropCleanCommands(config); len(dropped) > 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  Ignoring the clean commands of %s in %s; pass --allow-commands to run them\n", strings.Join(dropped, ", "), configPath)
+		}
+	}
+
+	cleaner := cacheremover.NewCleaner(config)
+	if *trash {
+		cleaner.Trash = newTrash(config)
+	}
+	history := newHistory(config)
+
+	if *global && (*ui || *trash || *targetFree != "" || *freeBelow != "") {
+		fmt.Fprintf(os.Stderr, "Error: --global cannot be combined with --ui, --trash, --target-free or --when-free-below\n")
+		os.Exit(2)
+	}
+
 	// Launch interactive TUI if requested
 	if *ui {
 		fmt.Println("🚀 Launching Interactive TUI Cache Remover...")
-		if err := runInteractiveUI(*rootDir); err != nil {
+		uiScanner := cacheremover.NewScanner(config)
+		uiScanner.MaxDepth = *maxDepth
+		uiScanner.SkipHidden = true
+		uiScanner.IncludeTracked = *tracked
+		uiScanner.GitActivity = *gitActivity
+		uiScanner.OneFileSystem = *oneFS
+		if !*noIndex {
+			uiScanner.Index = openIndex(config)
+		}
+		filter.configure(uiScanner)
+		if err := runInteractiveUI(*rootDir, uiScanner, cleaner, history, filter, *workers); err != nil {
 			fmt.Printf("Error running interactive UI: %v\n", err)
 			os.Exit(1)
 		}
 		return
 	}
 
-	fmt.Printf("🧹 Cache Remover Utility\n")
-	fmt.Printf("Scanning directory: %s\n", *rootDir)
-	fmt.Printf("Workers: %d\n", *workers)
-	if *dryRun {
-		fmt.Printf("🔍 DRY RUN MODE - No files will be removed\n")
-	}
-	
-	// Display supported project types for transparency
-	fmt.Printf("🔧 Supported project types: ")
-	var typeNames []string
-	for _, pt := range config.ProjectTypes {
-		typeNames = append(typeNames, pt.Name)
+	opts := cleanOptions{
+		workers:     *workers,
+		dryRun:      *dryRun,
+		verbose:     *verbose,
+		interactive: *interactive,
+		trash:       *trash,
+		filter:      filter,
+		targetFree:  sizeFlag("target-free", *targetFree),
 	}
-	fmt.Printf("%s\n", strings.Join(typeNames, ", "))
-	
-	fmt.Printf("💡 Tip: Use --ui for terminal interface or --web for browser interface\n")
-	fmt.Println()
-
-	startTime := time.Now()
-	stats := &CleanupStats{}
-
-	projects := findProjects(*rootDir, *maxDepth, *verbose)
-	fmt.Printf("Found %d projects\n\n", len(projects))
-
-	if len(projects) == 0 {
-		fmt.Println("No projects found.")
+	if *global {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		runGlobal(ctx, config, cleaner, history, opts, *output, os.Stdout)
 		return
 	}
 
-	processProjects(projects, *workers, *dryRun, *verbose, *interactive, stats)
-
-	stats.ProcessingTime = time.Since(startTime)
-	printStats(stats)
-}
-
-func isCacheDirectory(dirName string) bool {
-	config, _ := loadConfig()
-	// Check if this directory name matches any known cache directory patterns
-	for _, projectType := range config.ProjectTypes {
-		for _, cacheDir := range projectType.CacheConfig.Directories {
-			if dirName == cacheDir {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func findProjects(rootDir string, maxDepth int, verbose bool) []string {
-	var projects []string
-	var mu sync.Mutex
-
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if verbose {
-				fmt.Printf("⚠️  Warning: Cannot access %s: %v\n", path, err)
-			}
-			return nil
+	if *freeBelow != "" {
+		if *trash {
+			fmt.Fprintf(os.Stderr, "Error: --when-free-below cannot be combined with --trash, which does not free space\n")
+			os.Exit(2)
 		}
-
-		if !info.IsDir() {
-			return nil
+		if opts.targetFree > 0 {
+			fmt.Fprintf(os.Stderr, "Error: --when-free-below cannot be combined with --target-free\n")
+			os.Exit(2)
 		}
 
-		depth := strings.Count(strings.TrimPrefix(path, rootDir), string(os.PathSeparator))
-		if depth > maxDepth {
-			return filepath.SkipDir
+		// Keep stdout clean for machine-readable output
+		status := io.Writer(os.Stdout)
+		if *output != outputText {
+			status = os.Stderr
 		}
-
-		// Skip descending into cache directories - they're meant to be removed as units
-		if isCacheDirectory(info.Name()) {
-			if verbose {
-				fmt.Printf("⏭️  Skipping cache directory: %s\n", path)
-			}
-			return filepath.SkipDir
+		free, err := checkFreeSpace(status, *rootDir, *freeBelow, *freeUntil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
-
-		if isProjectDirectory(path) {
-			mu.Lock()
-			projects = append(projects, path)
-			mu.Unlock()
-			if verbose {
-				fmt.Printf("📁 Found project: %s\n", path)
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		fmt.Printf("Error scanning directories: %v\n", err)
-	}
-
-	return projects
-}
-
-func isProjectDirectory(dir string) bool {
-	config, _ := loadConfig()
-	for _, projectType := range config.ProjectTypes {
-		for _, indicator := range projectType.Indicators {
-			indicatorPath := filepath.Join(dir, indicator)
-			if _, err := os.Stat(indicatorPath); err == nil {
-				return true
+		opts.freeSpace = free
+		if free.Target == 0 {
+			if *output != outputText {
+				// Scripts still get a document, with nothing cleaned
+				out.begin(*rootDir, opts, config)
+				out.end(&cacheremover.CleanupStats{})
 			}
+			return
 		}
-	}
-	return false
-}
-
-func processProjects(projects []string, workers int, dryRun, verbose, interactive bool, stats *CleanupStats) {
-	projectChan := make(chan string, len(projects))
-	var wg sync.WaitGroup
-
-	for i := 0; i < workers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for project := range projectChan {
-				processProject(project, dryRun, verbose, interactive, stats)
-			}
-		}()
+		opts.targetFree = free.Target
 	}
 
-	for _, project := range projects {
-		projectChan <- project
-	}
-	close(projectChan)
+	out = &historyReporter{reporter: out, history: history}
+	out.begin(*rootDir, opts, config)
 
-	wg.Wait()
-}
+	// Stop scanning and cleaning gracefully on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-func processProject(projectPath string, dryRun, verbose, interactive bool, stats *CleanupStats) {
-	projectType := detectProjectType(projectPath)
-	if projectType == nil {
-		return
-	}
-
-	stats.IncrementProjects()
+	startTime := time.Now()
+	stats := &cacheremover.CleanupStats{}
 
-	if verbose {
-		fmt.Printf("🔍 Processing %s project: %s\n", projectType.Name, projectPath)
+	scanner := newScanner(config, *maxDepth, out)
+	scanner.IncludeTracked = *tracked
+	scanner.GitActivity = *gitActivity
+	scanner.OneFileSystem = *oneFS
+	if !*noIndex {
+		scanner.Index = openIndex(config)
 	}
-
-	cacheItems := findCacheItems(projectPath, projectType.CacheConfig)
-	if len(cacheItems) == 0 {
-		if verbose {
-			fmt.Printf("✅ No cache found in: %s\n", projectPath)
-		}
-		return
+	filter.configure(scanner)
+	if opts.targetFree > 0 {
+		scanner.MeasureActivity = true // Needed to rank candidates by age
 	}
 
-	totalSize := int64(0)
-	for _, item := range cacheItems {
-		totalSize += item.Size
+	found, err := scanner.Scan(ctx, *rootDir)
+	if err != nil {
+		out.warning("error scanning directories: %v", err)
+	} else if err := scanner.Index.Save(); err != nil {
+		out.warning("cannot save scan index: %v", err)
 	}
+	out.scanned(len(found))
 
-	fmt.Printf("🗂️  %s (%s): %d cache items (%s)\n",
-		filepath.Base(projectPath),
-		projectType.Name,
-		len(cacheItems),
-		formatBytes(totalSize))
-
-	if interactive && !dryRun {
-		fmt.Printf("Remove cache for %s? [y/N]: ", projectPath)
-		reader := bufio.NewReader(os.Stdin)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "y" && response != "yes" {
-			fmt.Printf("⏭️  Skipped: %s\n", projectPath)
-			return
-		}
+	projects := make([]*cacheremover.Project, len(found))
+	for i := range found {
+		projects[i] = &found[i]
 	}
-
-	if dryRun {
-		fmt.Printf("🔍 Would remove %d items (%s) from: %s\n",
-			len(cacheItems), formatBytes(totalSize), projectPath)
-		for _, item := range cacheItems {
-			fmt.Printf("  - %s (%s)\n", item.Path, formatBytes(item.Size))
-		}
-		// Add to stats even in dry-run mode to show potential savings
-		stats.Add(len(cacheItems), totalSize)
+	if opts.targetFree > 0 {
+		processTarget(ctx, cleaner, projects, opts, out, stats)
 	} else {
-		removedItems, removedSize := removeCacheItems(cacheItems, verbose)
-		if removedItems > 0 {
-			fmt.Printf("✅ Removed %d items (%s) from: %s\n",
-				removedItems, formatBytes(removedSize), projectPath)
-		}
-		stats.Add(removedItems, removedSize)
+		processProjects(ctx, cleaner, projects, opts, out, stats)
 	}
-	fmt.Println()
-}
 
-func detectProjectType(projectPath string) *ProjectType {
-	config, _ := loadConfig()
-	for _, projectType := range config.ProjectTypes {
-		for _, indicator := range projectType.Indicators {
-			indicatorPath := filepath.Join(projectPath, indicator)
-			if _, err := os.Stat(indicatorPath); err == nil {
-				return &projectType
-			}
-		}
-	}
-	return nil
+	stats.ProcessingTime = time.Since(startTime)
+	out.end(stats)
 }
 
-func findCacheItems(projectPath string, config CacheConfig) []CacheItem {
-	var items []CacheItem
-	processedPaths := make(map[string]bool) // Track paths to avoid double-counting
-
-	// First: Collect cache directories (search recursively for cache directory names)
-	for _, dir := range config.Directories {
-		// Check root level first (most common case)
-		dirPath := filepath.Join(projectPath, dir)
-		if size := getDirSize(dirPath); size > 0 {
-			items = append(items, CacheItem{
-				Path: dirPath,
-				Size: size,
-				Type: "directory",
-			})
-			processedPaths[dirPath] = true
-		}
-		
-		// Then search recursively for nested cache directories
-		filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil || !info.IsDir() {
-				return nil
-			}
-			
-			// Skip if already processed
-			if processedPaths[path] {
-				return filepath.SkipDir
-			}
-			
-			// Check if this directory matches a cache directory name
-			if info.Name() == dir {
-				if size := getDirSize(path); size > 0 {
-					items = append(items, CacheItem{
-						Path: path,
-						Size: size,
-						Type: "directory",
-					})
-					processedPaths[path] = true
-					return filepath.SkipDir // Don't traverse into this cache directory
-				}
-			}
-			
-			return nil
-		})
-	}
-
-	// Second: Collect individual cache files
-	for _, file := range config.Files {
-		filePath := filepath.Join(projectPath, file)
-		if !processedPaths[filePath] {
-			if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
-				items = append(items, CacheItem{
-					Path: filePath,
-					Size: info.Size(),
-					Type: "file",
-				})
-			}
-		}
+// sizeFlag parses the value of a size flag, exiting on invalid input. An
+// empty value means no limit.
+func sizeFlag(name, value string) int64 {
+	if value == "" {
+		return 0
 	}
-
-	// Third: Collect files by extension (but skip those already inside processed cache directories)
-	if len(config.Extensions) > 0 {
-		filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil
-			}
-
-			// Skip if this file is inside an already-processed cache directory
-			for processedDir := range processedPaths {
-				if strings.HasPrefix(path, processedDir+string(filepath.Separator)) {
-					return nil // Skip files inside cache directories we already counted
-				}
-			}
-
-			// Process files with matching extensions
-			if !info.IsDir() {
-				for _, ext := range config.Extensions {
-					if strings.HasSuffix(info.Name(), ext) {
-						items = append(items, CacheItem{
-							Path: path,
-							Size: info.Size(),
-							Type: "file",
-						})
-						break
-					}
-				}
-			}
-			return nil
-		})
+	size, err := parseSize(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --%s: %v\n", name, err)
+		os.Exit(2)
 	}
-
-	return items
+	return size
 }
 
-func getDirSize(dirPath string) int64 {
-	// Always use full recursive scanning for accurate size calculations
-	// Performance optimization happens during project discovery, not size calculation
-
-	var size int64
-	filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			// Skip files we can't access but continue walking
-			return nil
-		}
-		if !info.IsDir() {
-			// Check for potential overflow
-			if size > 0 && info.Size() > 0 && size > (9223372036854775807-info.Size()) {
-				return filepath.SkipDir // Stop walking to prevent overflow
-			}
-			size += info.Size()
-		}
-		return nil
-	})
-	return size
+// newScanner creates a scanner that forwards its events to the reporter
+func newScanner(config *cacheremover.Config, maxDepth int, out reporter) *cacheremover.Scanner {
+	scanner := cacheremover.NewScanner(config)
+	scanner.MaxDepth = maxDepth
+	scanner.OnEvent = out.scanEvent
+	return scanner
 }
 
-// getOptimizedCacheDirSize calculates directory size without walking through all contents
-// This provides significant performance improvement for large cache directories like node_modules
-func getOptimizedCacheDirSize(dirPath string) int64 {
-	// Check if directory exists and is accessible
-	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
-		return 0
+// openIndex loads the scan index. Scanning works without it, so an index
+// that cannot be read is only a warning.
+func openIndex(config *cacheremover.Config) *cacheremover.ScanIndex {
+	dir := config.Settings.IndexDir
+	if dir == "" {
+		dir = cacheremover.DefaultIndexDir()
 	}
-
-	entries, err := os.ReadDir(dirPath)
+	index, err := cacheremover.OpenScanIndex(dir, config)
 	if err != nil {
-		// If we can't read the directory (permissions, etc.), fall back to standard method
-		return getDirSizeFallback(dirPath)
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: cannot read scan index %s: %v\n", index.Path(), err)
 	}
-
-	var totalSize int64
-	for _, entry := range entries {
-		entryPath := filepath.Join(dirPath, entry.Name())
-
-		// Skip symlinks to avoid infinite loops and potential issues
-		if entry.Type()&os.ModeSymlink != 0 {
-			continue
-		}
-
-		if entry.IsDir() {
-			// For subdirectories, get their size recursively
-			// but limit depth to avoid performance issues
-			totalSize += getDirectorySizeWithLimit(entryPath, 3)
-		} else {
-			// For files, get their size directly
-			if info, err := entry.Info(); err == nil {
-				// Ensure we don't overflow int64
-				if totalSize > 0 && info.Size() > 0 && totalSize > (9223372036854775807-info.Size()) {
-					// Handle potential overflow
-					return totalSize
-				}
-				totalSize += info.Size()
-			}
-		}
-	}
-
-	return totalSize
+	return index
 }
 
-// getDirectorySizeWithLimit calculates directory size with a maximum depth limit
-func getDirectorySizeWithLimit(dirPath string, maxDepth int) int64 {
-	if maxDepth <= 0 {
-		return 0
-	}
+func processProjects(ctx context.Context, cleaner *cacheremover.Cleaner, projects []*cacheremover.Project, opts cleanOptions, out reporter, stats *cacheremover.CleanupStats) {
+	runWorkers(ctx, opts.workers, len(projects), func(i int) {
+		processProject(ctx, cleaner, projects[i], opts, out, stats)
+	})
+}
 
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		// Skip directories we can't access
-		return 0
+// runWorkers calls work for jobs 0 to n-1 on at most workers goroutines,
+// since cleaning is bound by disk I/O rather than CPU. Jobs not started by
+// the time ctx is cancelled are skipped.
+func runWorkers(ctx context.Context, workers, n int, work func(i int)) {
+	jobs := make(chan int, n)
+	for i := 0; i < n; i++ {
+		jobs <- i
 	}
+	close(jobs)
 
-	var totalSize int64
-	for _, entry := range entries {
-		// Skip symlinks to prevent infinite loops
-		if entry.Type()&os.ModeSymlink != 0 {
-			continue
-		}
-
-		if !entry.IsDir() {
-			if info, err := entry.Info(); err == nil {
-				// Check for potential overflow
-				if totalSize > 0 && info.Size() > 0 && totalSize > (9223372036854775807-info.Size()) {
-					return totalSize
+	var wg sync.WaitGroup
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
 				}
-				totalSize += info.Size()
-			}
-		} else if maxDepth > 1 {
-			// Recurse into subdirectories with reduced depth
-			subPath := filepath.Join(dirPath, entry.Name())
-			subSize := getDirectorySizeWithLimit(subPath, maxDepth-1)
-
-			// Check for potential overflow
-			if totalSize > 0 && subSize > 0 && totalSize > (9223372036854775807-subSize) {
-				return totalSize
+				work(i)
 			}
-			totalSize += subSize
-		}
+		}()
 	}
-
-	return totalSize
-}
-
-// getDirSizeFallback is the original directory size calculation method
-// Used as fallback when optimized method fails
-func getDirSizeFallback(dirPath string) int64 {
-	var size int64
-	filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			// Skip files we can't access but continue walking
-			return nil
-		}
-		if !info.IsDir() {
-			// Check for potential overflow
-			if size > 0 && info.Size() > 0 && size > (9223372036854775807-info.Size()) {
-				return filepath.SkipDir // Stop walking to prevent overflow
-			}
-			size += info.Size()
-		}
-		return nil
-	})
-	return size
+	wg.Wait()
 }
 
-func removeCacheItems(items []CacheItem, verbose bool) (int, int64) {
-	removedItems := 0
-	removedSize := int64(0)
-
-	for _, item := range items {
-		if err := forceRemoveCacheDirectory(item.Path, verbose); err != nil {
-			// Always log removal failures, not just in verbose mode
-			fmt.Printf("❌ Failed to remove %s: %v\n", item.Path, err)
-		} else {
-			removedItems++
-			removedSize += item.Size
-			if verbose {
-				fmt.Printf("🗑️  Removed: %s (%s)\n", item.Path, formatBytes(item.Size))
-			}
-		}
+func processProject(ctx context.Context, cleaner *cacheremover.Cleaner, project *cacheremover.Project, opts cleanOptions, out reporter, stats *cacheremover.CleanupStats) {
+	if !selectProject(project, opts, out) {
+		return
 	}
-
-	return removedItems, removedSize
+	cleanProject(ctx, cleaner, project, opts, out, stats)
 }
 
-// forceRemoveCacheDirectory aggressively removes cache directories with multiple strategies
-func forceRemoveCacheDirectory(path string, verbose bool) error {
-	// Check if path exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil // Already gone, consider it success
+// selectProject applies the run's filter to a scanned project. It reports
+// false, after reporting why, when the project is not part of the run.
+func selectProject(project *cacheremover.Project, opts cleanOptions, out reporter) bool {
+	if opts.filter.isActive(project) {
+		report := newProjectReport(project)
+		report.Status = statusActive
+		out.projectFinished(report)
+		return false
 	}
 
-	// Strategy 1: Try standard RemoveAll first (fastest when it works)
-	if err := os.RemoveAll(path); err == nil {
-		return nil
+	opts.filter.dropSmallItems(project)
+	if opts.filter.isTooSmall(project) {
+		report := newProjectReport(project)
+		report.Status = statusTooSmall
+		out.projectFinished(report)
+		return false
 	}
+	return true
+}
 
-	// Strategy 2: Try to fix permissions and retry
-	if err := makeWritableRecursive(path); err == nil {
-		if err := os.RemoveAll(path); err == nil {
-			return nil
-		}
-	}
+// cleanProject removes the cache items of a scanned project, or reports
+// what would be removed in a dry run. It returns the bytes reclaimed.
+func cleanProject(ctx context.Context, cleaner *cacheremover.Cleaner, project *cacheremover.Project, opts cleanOptions, out reporter, stats *cacheremover.CleanupStats) int64 {
+	stats.IncrementProjects()
+	out.projectStarted(project)
 
-	// Strategy 3: Manual recursive removal with permission fixing
-	if err := forceRemoveRecursive(path, verbose); err == nil {
-		return nil
+	report := newProjectReport(project)
+	if len(project.Items) == 0 {
+		out.projectFinished(report)
+		return 0
 	}
 
-	// Strategy 4: Last resort - try system command (Unix-like systems only)
-	if err := forceRemoveWithSystemCommand(path); err == nil {
-		return nil
+	if opts.interactive && !opts.dryRun && !confirm(fmt.Sprintf("Remove cache for %s?", project.Path)) {
+		report.Status = statusSkipped
+		out.projectFinished(report)
+		return 0
 	}
 
-	// If all strategies fail, return the error
-	return fmt.Errorf("all removal strategies failed for cache directory: %s", path)
-}
-
-// makeWritableRecursive makes all files and directories writable
-func makeWritableRecursive(path string) error {
-	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip files we can't access
-		}
-		
-		// Make file/directory writable
-		return os.Chmod(filePath, info.Mode()|0200) // Add write permission
-	})
-}
-
-// forceRemoveRecursive manually removes files and directories with permission fixing
-func forceRemoveRecursive(path string, verbose bool) error {
-	// First pass: fix all permissions
-	filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip files we can't access
-		}
-		// Make writable and executable (for directories)
-		newMode := info.Mode() | 0200 // Add write permission
-		if info.IsDir() {
-			newMode |= 0100 // Add execute permission for directories
-		}
-		os.Chmod(filePath, newMode)
-		return nil
-	})
-
-	// Second pass: remove everything (start from deepest level)
-	var allPaths []string
-	filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		if err == nil {
-			allPaths = append(allPaths, filePath)
-		}
-		return nil
-	})
-
-	// Sort paths by depth (deepest first) to avoid "directory not empty" errors
-	// Reverse the slice so we process deepest paths first
-	for i := len(allPaths) - 1; i >= 0; i-- {
-		filePath := allPaths[i]
-		if info, err := os.Stat(filePath); err == nil {
-			if info.IsDir() {
-				if err := os.Remove(filePath); err != nil && verbose {
-					fmt.Printf("⚠️  Warning: Cannot remove directory %s: %v\n", filePath, err)
-				}
-			} else {
-				if err := os.Remove(filePath); err != nil && verbose {
-					fmt.Printf("⚠️  Warning: Cannot remove file %s: %v\n", filePath, err)
-				}
+	var reclaimed int64
+	if opts.dryRun {
+		report.Status = statusWouldRemove
+		if !opts.trash {
+			for _, planned := range project.CleanCommands() {
+				report.Commands = append(report.Commands, newCommandReport(planned, commandPlanned))
 			}
 		}
+		// Add to stats even in dry-run mode to show potential savings
+		stats.Add(len(project.Items), project.TotalSize, project.ApparentSize)
+		reclaimed = project.TotalSize
+	} else {
+		result, _ := cleaner.CleanProject(ctx, project)
+		report.setResult(result)
+		stats.Add(len(result.Removed), result.BytesRemoved, result.ApparentBytesRemoved)
+		reclaimed = result.BytesRemoved
 	}
-
-	// Final check: is the main directory gone?
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil // Success!
-	}
-	
-	return fmt.Errorf("directory still exists after manual removal")
+	out.projectFinished(report)
+	return reclaimed
 }
 
-// forceRemoveWithSystemCommand uses system commands as last resort (Unix-like systems)
-func forceRemoveWithSystemCommand(path string) error {
-	// Only try this on Unix-like systems
-	if filepath.Separator != '/' {
-		return fmt.Errorf("system command removal not supported on this platform")
-	}
-
-	// Use rm -rf as last resort for cache directories
-	cmd := exec.Command("rm", "-rf", path)
-	return cmd.Run()
+// confirm asks a yes/no question on stdin; anything but yes means no
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N]: ", prompt)
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
 }
 
 func formatBytes(bytes int64) string {
@@ -669,19 +400,40 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-func printStats(stats *CleanupStats) {
-	fmt.Printf("📊 Cleanup Statistics:\n")
-	fmt.Printf("   Projects processed: %d\n", stats.TotalProjects)
-	fmt.Printf("   Cache items removed: %d\n", stats.TotalCacheItems)
-	fmt.Printf("   Total space reclaimed: %s\n", formatBytes(stats.TotalSizeRemoved))
-	fmt.Printf("   Processing time: %v\n", stats.ProcessingTime)
+// formatAge formats a duration as a short age such as "45m", "3d" or "5mo"
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < day:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 30*day:
+		return fmt.Sprintf("%dd", int(d/day))
+	case d < 365*day:
+		return fmt.Sprintf("%dmo", int(d/(30*day)))
+	default:
+		return fmt.Sprintf("%dy", int(d/(365*day)))
+	}
+}
+
+func printStats(w io.Writer, stats *cacheremover.CleanupStats) {
+	fmt.Fprintf(w, "📊 Cleanup Statistics:\n")
+	fmt.Fprintf(w, "   Projects processed: %d\n", stats.TotalProjects)
+	fmt.Fprintf(w, "   Cache items removed: %d\n", stats.TotalCacheItems)
+	fmt.Fprintf(w, "   Total space reclaimed: %s\n", formatBytes(stats.TotalSizeRemoved))
+	if stats.TotalApparentSize != stats.TotalSizeRemoved {
+		// Hard links, sparse files and block rounding make these differ
+		fmt.Fprintf(w, "   Apparent size of removed files: %s\n", formatBytes(stats.TotalApparentSize))
+	}
+	fmt.Fprintf(w, "   Processing time: %v\n", stats.ProcessingTime)
 	if stats.ProcessingTime.Seconds() > 0 {
-		fmt.Printf("   Average speed: %.2f MB/s\n",
+		fmt.Fprintf(w, "   Average speed: %.2f MB/s\n",
 			float64(stats.TotalSizeRemoved)/(1024*1024)/stats.ProcessingTime.Seconds())
 	}
 }
 
-func listProjectTypes(config *Config) {
+func listProjectTypes(config *cacheremover.Config) {
 	fmt.Printf("📋 Supported Project Types (%d total):\n\n", len(config.ProjectTypes))
 
 	for _, pt := range config.ProjectTypes {
@@ -697,6 +449,10 @@ func listProjectTypes(config *Config) {
 			fmt.Printf("   Cache Extensions: %s\n", strings.Join(pt.CacheConfig.Extensions, ", "))
 		}
 
+		if pt.CleanCommand != nil {
+			fmt.Printf("   Clean Command: %s (%s)\n", pt.CleanCommand.String(), commandMode(pt.CleanCommand))
+		}
+
 		fmt.Println()
 	}
 
@@ -1,66 +1,91 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"cache-remover-utility/cacheremover"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
-func TestDetectProjectType(t *testing.T) {
-	// Create a temporary directory for testing
-	tempDir := t.TempDir()
+func newTestRun(t *testing.T) (*cacheremover.Scanner, *cacheremover.Cleaner, reporter) {
+	t.Helper()
+	return newTestRunWithOutput(t, outputText, io.Discard)
+}
 
-	// Test Node.js project detection
-	packageJSONPath := filepath.Join(tempDir, "package.json")
-	file, err := os.Create(packageJSONPath)
+func newTestRunWithOutput(t *testing.T, format string, w io.Writer) (*cacheremover.Scanner, *cacheremover.Cleaner, reporter) {
+	t.Helper()
+	config := cacheremover.DefaultConfig()
+	out, err := newReporter(format, w, false)
 	if err != nil {
-		t.Fatalf("Failed to create package.json: %v", err)
-	}
-	file.Close()
-
-	projectType := detectProjectType(tempDir)
-	if projectType == nil || projectType.Name != "Node.js" {
-		t.Errorf("Expected Node.js project type, got %v", projectType)
+		t.Fatalf("newReporter failed: %v", err)
 	}
+	return newScanner(&config, config.Settings.MaxDepth, out), cacheremover.NewCleaner(&config), out
+}
 
-	// Clean up
-	os.Remove(packageJSONPath)
-
-	// Test Python project detection
-	requirementsPath := filepath.Join(tempDir, "requirements.txt")
-	file, err = os.Create(requirementsPath)
+func scanTestProjects(t *testing.T, scanner *cacheremover.Scanner, rootDir string) []*cacheremover.Project {
+	t.Helper()
+	found, err := scanner.Scan(context.Background(), rootDir)
 	if err != nil {
-		t.Fatalf("Failed to create requirements.txt: %v", err)
+		t.Fatalf("Scan failed: %v", err)
 	}
-	file.Close()
-
-	projectType = detectProjectType(tempDir)
-	if projectType == nil || projectType.Name != "Python" {
-		t.Errorf("Expected Python project type, got %v", projectType)
+	projects := make([]*cacheremover.Project, len(found))
+	for i := range found {
+		projects[i] = &found[i]
 	}
+	return projects
 }
 
-func TestIsProjectDirectory(t *testing.T) {
+func TestLoadConfigRejectsInvalidFile(t *testing.T) {
 	tempDir := t.TempDir()
+	invalid := filepath.Join(tempDir, "config.json")
+	config := cacheremover.DefaultConfig()
+	config.ProjectTypes[0].CacheConfig.Directories = append(config.ProjectTypes[0].CacheConfig.Directories, "re:[unclosed")
+	data, _ := json.Marshal(config)
+	os.WriteFile(invalid, data, 0644)
+
+	defer func(paths []string) { configPaths = paths }(configPaths)
+
+	// A missing file is skipped, an invalid one stops the search
+	configPaths = []string{filepath.Join(tempDir, "missing.json"), invalid}
+	if _, path, err := loadConfig(); err == nil || path != invalid {
+		t.Errorf("Expected an error for %s, got %q (%v)", invalid, path, err)
+	}
 
-	// Initially should not be a project directory
-	if isProjectDirectory(tempDir) {
-		t.Error("Empty directory should not be detected as project")
+	configPaths = []string{filepath.Join(tempDir, "missing.json")}
+	if loaded, path, err := loadConfig(); err != nil || path != "" || loaded == nil {
+		t.Errorf("Expected the defaults without any file, got %q (%v)", path, err)
 	}
+}
 
-	// Create a Go project indicator
-	goModPath := filepath.Join(tempDir, "go.mod")
-	file, err := os.Create(goModPath)
-	if err != nil {
-		t.Fatalf("Failed to create go.mod: %v", err)
+func TestLocalConfigCannotRunCommands(t *testing.T) {
+	for _, path := range configPaths {
+		if want := path != "config.json" && path != "cache-remover-config.json"; trustedConfig(path) != want {
+			t.Errorf("trustedConfig(%s) = %v, want %v", path, !want, want)
+		}
 	}
-	file.Close()
 
-	// Now should be detected as project directory
-	if !isProjectDirectory(tempDir) {
-		t.Error("Directory with go.mod should be detected as project")
+	config := cacheremover.DefaultConfig()
+	config.ProjectTypes[5].CleanCommand = &cacheremover.CleanCommand{Command: []string{"cargo", "clean"}}
+	if dropped := dropCleanCommands(&config); len(dropped) != 1 || dropped[0] != config.ProjectTypes[5].Name {
+		t.Errorf("Expected the Rust command dropped, got %v", dropped)
+	}
+	for _, pt := range config.ProjectTypes {
+		if pt.CleanCommand != nil {
+			t.Errorf("%s still has a clean command", pt.Name)
+		}
 	}
 }
 
@@ -84,44 +109,27 @@ func TestFormatBytes(t *testing.T) {
 	}
 }
 
-func TestFindCacheItems(t *testing.T) {
-	tempDir := t.TempDir()
-
-	// Create some cache directories and files
-	nodemodulesDir := filepath.Join(tempDir, "node_modules")
-	os.MkdirAll(nodemodulesDir, 0755)
-
-	// Create a file inside node_modules
-	testFile := filepath.Join(nodemodulesDir, "test.js")
-	file, err := os.Create(testFile)
-	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
-	}
-	file.WriteString("console.log('test');")
-	file.Close()
-
-	config := CacheConfig{
-		Directories: []string{"node_modules"},
-		Files:       []string{},
-		Extensions:  []string{},
-	}
-
-	items := findCacheItems(tempDir, config)
-	if len(items) != 1 {
-		t.Errorf("Expected 1 cache item, got %d", len(items))
-	}
-
-	if items[0].Path != nodemodulesDir {
-		t.Errorf("Expected cache item path %s, got %s", nodemodulesDir, items[0].Path)
+func TestFormatAge(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{5 * time.Minute, "5m"},
+		{3 * time.Hour, "3h"},
+		{12 * day, "12d"},
+		{95 * day, "3mo"},
+		{800 * day, "2y"},
 	}
 
-	if items[0].Size <= 0 {
-		t.Error("Cache item should have size > 0")
+	for _, test := range tests {
+		result := formatAge(test.age)
+		if result != test.expected {
+			t.Errorf("formatAge(%v) = %s, expected %s", test.age, result, test.expected)
+		}
 	}
 }
 
-// Integration Tests
-
 func TestFullWorkflowIntegration(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -130,15 +138,18 @@ func TestFullWorkflowIntegration(t *testing.T) {
 	setupTestProject(t, filepath.Join(tempDir, "python-api"), "python-api", "Python")
 	setupTestProject(t, filepath.Join(tempDir, "java-service"), "java-service", "Java")
 
+	scanner, cleaner, out := newTestRun(t)
+
 	// Test project discovery
-	projects := findProjects(tempDir, 10, false)
+	projects := scanTestProjects(t, scanner, tempDir)
 	if len(projects) != 3 {
 		t.Errorf("Expected 3 projects, found %d", len(projects))
 	}
 
 	// Test cache detection and cleanup
-	stats := &CleanupStats{}
-	processProjects(projects, 1, true, false, false, stats) // dry run
+	stats := &cacheremover.CleanupStats{}
+	opts := cleanOptions{workers: 1, dryRun: true}
+	processProjects(context.Background(), cleaner, projects, opts, out, stats)
 
 	if stats.TotalProjects != 3 {
 		t.Errorf("Expected 3 projects processed, got %d", stats.TotalProjects)
@@ -149,36 +160,6 @@ func TestFullWorkflowIntegration(t *testing.T) {
 	}
 }
 
-func TestCacheDirectorySkipping(t *testing.T) {
-	tempDir := t.TempDir()
-
-	// Create nested structure with cache directories
-	nodePath := filepath.Join(tempDir, "project")
-	nodeModulesPath := filepath.Join(nodePath, "node_modules")
-	nestedPath := filepath.Join(nodeModulesPath, "some-package", "node_modules")
-
-	os.MkdirAll(nestedPath, 0755)
-
-	// Create package.json to make it a project
-	packageJSON := filepath.Join(nodePath, "package.json")
-	os.WriteFile(packageJSON, []byte(`{"name": "test"}`), 0644)
-
-	// Create a file deep in nested node_modules
-	testFile := filepath.Join(nestedPath, "test.js")
-	os.WriteFile(testFile, []byte("test content"), 0644)
-
-	// Test that we skip descending into cache directories
-	projects := findProjects(tempDir, 10, true)
-	if len(projects) != 1 {
-		t.Errorf("Expected 1 project, found %d", len(projects))
-	}
-
-	// The nested node_modules should not cause additional projects to be found
-	if projects[0] != nodePath {
-		t.Errorf("Expected project path %s, got %s", nodePath, projects[0])
-	}
-}
-
 func TestConcurrentProcessing(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -189,15 +170,17 @@ func TestConcurrentProcessing(t *testing.T) {
 		setupTestProject(t, projectDir, "test-project", "Node.js")
 	}
 
-	projects := findProjects(tempDir, 10, false)
+	scanner, cleaner, out := newTestRun(t)
+	projects := scanTestProjects(t, scanner, tempDir)
 	if len(projects) != projectCount {
 		t.Errorf("Expected %d projects, found %d", projectCount, len(projects))
 	}
 
 	// Test concurrent processing with multiple workers
-	stats := &CleanupStats{}
+	stats := &cacheremover.CleanupStats{}
 	startTime := time.Now()
-	processProjects(projects, 3, true, false, false, stats) // 3 workers, dry run
+	opts := cleanOptions{workers: 3, dryRun: true}
+	processProjects(context.Background(), cleaner, projects, opts, out, stats)
 	processingTime := time.Since(startTime)
 
 	if stats.TotalProjects != projectCount {
@@ -224,9 +207,11 @@ func TestRealCleanupWorkflow(t *testing.T) {
 	}
 
 	// Perform actual cleanup (not dry run)
-	projects := findProjects(tempDir, 10, false)
-	stats := &CleanupStats{}
-	processProjects(projects, 1, false, false, false, stats) // actual cleanup
+	scanner, cleaner, out := newTestRun(t)
+	projects := scanTestProjects(t, scanner, tempDir)
+	stats := &cacheremover.CleanupStats{}
+	opts := cleanOptions{workers: 1}
+	processProjects(context.Background(), cleaner, projects, opts, out, stats)
 
 	// Verify cache was removed
 	if _, err := os.Stat(nodeModulesPath); !os.IsNotExist(err) {
@@ -238,31 +223,579 @@ func TestRealCleanupWorkflow(t *testing.T) {
 	}
 }
 
-func TestErrorHandling(t *testing.T) {
+func TestJSONOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	setupTestProject(t, filepath.Join(tempDir, "react-app"), "react-app", "Node.js")
+	setupTestProject(t, filepath.Join(tempDir, "python-api"), "python-api", "Python")
+
+	var buf bytes.Buffer
+	scanner, cleaner, out := newTestRunWithOutput(t, outputJSON, &buf)
+	opts := cleanOptions{workers: 2, dryRun: true}
+
+	out.begin(tempDir, opts, scanner.Config())
+	projects := scanTestProjects(t, scanner, tempDir)
+	out.scanned(len(projects))
+	stats := &cacheremover.CleanupStats{}
+	processProjects(context.Background(), cleaner, projects, opts, out, stats)
+	out.end(stats)
+
+	var doc jsonDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if len(doc.Projects) != 2 {
+		t.Fatalf("Expected 2 projects, got %d", len(doc.Projects))
+	}
+	if doc.Projects[0].Type != "Python" || doc.Projects[1].Type != "Node.js" {
+		t.Errorf("Expected projects sorted by path, got %s then %s", doc.Projects[0].Type, doc.Projects[1].Type)
+	}
+	for _, project := range doc.Projects {
+		if project.Status != statusWouldRemove || len(project.Items) == 0 {
+			t.Errorf("Unexpected report for %s: %+v", project.Path, project)
+		}
+	}
+	if doc.Stats.TotalProjects != 2 || doc.Stats.TotalSizeRemoved <= 0 {
+		t.Errorf("Unexpected stats: %+v", doc.Stats)
+	}
+}
+
+func TestNDJSONOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	setupTestProject(t, filepath.Join(tempDir, "java-service"), "java-service", "Java")
+
+	var buf bytes.Buffer
+	scanner, cleaner, out := newTestRunWithOutput(t, outputNDJSON, &buf)
+	opts := cleanOptions{workers: 1}
+
+	out.begin(tempDir, opts, scanner.Config())
+	projects := scanTestProjects(t, scanner, tempDir)
+	out.scanned(len(projects))
+	stats := &cacheremover.CleanupStats{}
+	processProjects(context.Background(), cleaner, projects, opts, out, stats)
+	out.end(stats)
+
+	var events []map[string]interface{}
+	lines := bufio.NewScanner(&buf)
+	for lines.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(lines.Bytes(), &event); err != nil {
+			t.Fatalf("Line is not valid JSON: %v\n%s", err, lines.Text())
+		}
+		events = append(events, event)
+	}
+
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, event["event"].(string))
+	}
+	if strings.Join(kinds, ",") != "start,scanned,project,summary" {
+		t.Fatalf("Unexpected event sequence: %v", kinds)
+	}
+
+	project := events[2]
+	if project["status"] != statusRemoved || project["removed_items"].(float64) != 1 {
+		t.Errorf("Unexpected project event: %v", project)
+	}
+}
+
+func TestOlderThanSkipsActiveProjects(t *testing.T) {
 	tempDir := t.TempDir()
+	oldProject := filepath.Join(tempDir, "old-app")
+	setupTestProject(t, oldProject, "old-app", "Node.js")
+	setupTestProject(t, filepath.Join(tempDir, "new-app"), "new-app", "Node.js")
+
+	// Make every file of old-app look untouched for 60 days
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	filepath.Walk(oldProject, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			os.Chtimes(path, old, old)
+		}
+		return nil
+	})
+
+	var buf bytes.Buffer
+	scanner, cleaner, out := newTestRunWithOutput(t, outputJSON, &buf)
+	opts := cleanOptions{workers: 2, dryRun: true, filter: projectFilter{olderThan: 30 * 24 * time.Hour}}
+	opts.filter.configure(scanner)
+
+	out.begin(tempDir, opts, scanner.Config())
+	stats := &cacheremover.CleanupStats{}
+	processProjects(context.Background(), cleaner, scanTestProjects(t, scanner, tempDir), opts, out, stats)
+	out.end(stats)
+
+	var doc jsonDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	statuses := make(map[string]string)
+	for _, project := range doc.Projects {
+		statuses[filepath.Base(project.Path)] = project.Status
+		if project.LastActivity == nil {
+			t.Errorf("Expected last activity for %s", project.Path)
+		}
+	}
+	if statuses["old-app"] != statusWouldRemove || statuses["new-app"] != statusActive {
+		t.Errorf("Unexpected statuses: %v", statuses)
+	}
+	if stats.TotalProjects != 1 {
+		t.Errorf("Active projects should not be counted, got %d projects", stats.TotalProjects)
+	}
+}
+
+// setupSizedProject creates a Node.js project whose node_modules holds a
+// lock file of size bytes. Its size on disk adds the blocks of the directory.
+func setupSizedProject(t *testing.T, projectDir string, size int) {
+	t.Helper()
+	os.MkdirAll(filepath.Join(projectDir, "node_modules"), 0755)
+	os.WriteFile(filepath.Join(projectDir, "package.json"), []byte("{}"), 0644)
+
+	// Random data, so filesystems that compress still allocate the blocks
+	blob := make([]byte, size)
+	rand.Read(blob)
+	if err := os.WriteFile(filepath.Join(projectDir, "node_modules", ".package-lock.json"), blob, 0644); err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+}
+
+// runJSON runs a dry run over rootDir with opts and returns the JSON document
+func runJSON(t *testing.T, rootDir string, opts cleanOptions) jsonDocument {
+	t.Helper()
 
-	// Create cache item that should fail to remove (but os.RemoveAll doesn't fail on non-existent)
-	// So let's create a file first, then remove it, then try to remove it again
-	testFile := filepath.Join(tempDir, "test-file")
-	os.WriteFile(testFile, []byte("test"), 0644)
-	os.Remove(testFile) // Remove it first
+	var buf bytes.Buffer
+	scanner, cleaner, out := newTestRunWithOutput(t, outputJSON, &buf)
+	opts.filter.configure(scanner)
+	scanner.MeasureActivity = true
+
+	out.begin(rootDir, opts, scanner.Config())
+	stats := &cacheremover.CleanupStats{}
+	projects := scanTestProjects(t, scanner, rootDir)
+	if opts.targetFree > 0 {
+		processTarget(context.Background(), cleaner, projects, opts, out, stats)
+	} else {
+		processProjects(context.Background(), cleaner, projects, opts, out, stats)
+	}
+	out.end(stats)
 
-	item := CacheItem{
-		Path: testFile,
-		Size: 100,
-		Type: "file",
+	var doc jsonDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
 	}
+	return doc
+}
+
+func TestMinProjectSize(t *testing.T) {
+	tempDir := t.TempDir()
+	setupSizedProject(t, filepath.Join(tempDir, "small"), 10*1024)
+	setupSizedProject(t, filepath.Join(tempDir, "large"), 30*1024)
 
-	// Test that removal of non-existent item succeeds (os.RemoveAll behavior)
-	removed, size := removeCacheItems([]CacheItem{item}, false)
+	opts := cleanOptions{workers: 2, dryRun: true, filter: projectFilter{minProjectSize: 20 * 1024}}
+	doc := runJSON(t, tempDir, opts)
 
-	// os.RemoveAll succeeds even if file doesn't exist
-	if removed != 1 {
-		t.Errorf("Expected 1 item removed (os.RemoveAll succeeds on non-existent), got %d", removed)
+	statuses := make(map[string]string)
+	for _, project := range doc.Projects {
+		statuses[filepath.Base(project.Path)] = project.Status
 	}
+	if statuses["small"] != statusTooSmall || statuses["large"] != statusWouldRemove {
+		t.Errorf("Unexpected statuses: %v", statuses)
+	}
+}
 
-	if size != 100 {
-		t.Errorf("Expected size 100 to be counted as removed, got %d", size)
+func TestTUIScanStreamsProjects(t *testing.T) {
+	tempDir := t.TempDir()
+	setupSizedProject(t, filepath.Join(tempDir, "small"), 10*1024)
+	setupSizedProject(t, filepath.Join(tempDir, "large"), 30*1024)
+	setupSizedProject(t, filepath.Join(tempDir, "group", "medium"), 20*1024)
+
+	config := cacheremover.DefaultConfig()
+	filter := projectFilter{minProjectSize: 20 * 1024}
+	m := initialModel(tempDir, cacheremover.NewScanner(&config), cacheremover.NewCleaner(&config), nil, filter, 1)
+
+	// Deliver the messages of the scan the way the program would
+	msg := loadProjects(context.Background(), m.scanner, tempDir, filter)()
+	for {
+		updated, _ := m.Update(msg)
+		m = updated.(model)
+		progress, ok := msg.(loadProgressMsg)
+		if !ok {
+			break
+		}
+		msg = progress.stream.next()
+	}
+
+	if m.loading || m.state != StateProjectList {
+		t.Fatalf("Expected the scan to be over, got loading=%v state=%v", m.loading, m.state)
+	}
+	if m.scanFound != 3 {
+		t.Errorf("Expected 3 projects discovered, got %d", m.scanFound)
+	}
+	var names []string
+	for _, project := range m.projects {
+		names = append(names, project.Project.Name)
+	}
+	if strings.Join(names, ",") != "large,medium" {
+		t.Errorf("Expected large and medium, largest first, got %v", names)
+	}
+	if m.tree == nil || m.tree.Root.ChildProjects != 2 {
+		t.Errorf("Expected both projects in the tree, got %+v", m.tree)
+	}
+}
+
+func TestFilteredLeavesProjectAlone(t *testing.T) {
+	member := &cacheremover.Project{
+		Items:     []cacheremover.CacheItem{{Path: "ui/node_modules", Size: 100}, {Path: "ui/dist", Size: 10}},
+		TotalSize: 110,
+	}
+	project := &cacheremover.Project{
+		Items:     append([]cacheremover.CacheItem(nil), member.Items...),
+		TotalSize: 110,
+		Members:   []*cacheremover.Project{member},
+	}
+
+	filtered := projectFilter{minItemSize: 50}.filtered(project)
+	if filtered == nil || len(filtered.Items) != 1 || filtered.TotalSize != 100 || len(filtered.Members[0].Items) != 1 {
+		t.Fatalf("Expected the small items dropped from the copy, got %+v", filtered)
+	}
+	if len(project.Items) != 2 || project.Items[1].Path != "ui/dist" || project.TotalSize != 110 || len(member.Items) != 2 {
+		t.Errorf("Expected the scanned project unchanged, got %+v", project)
+	}
+}
+
+func TestTreeRestoreState(t *testing.T) {
+	item := func(path string) ProjectItem {
+		return ProjectItem{Project: &Project{Name: filepath.Base(path), Path: path}}
+	}
+	projects := []ProjectItem{item("root/a/one"), item("root/b/two")}
+	old := buildProjectTree(projects, "root")
+	old.CurrentIndex = 1
+	old.expandNode() // Expand b
+	old.moveDown()   // On two
+
+	projects = append(projects, item("root/a/three"))
+	tree := buildProjectTree(projects, "root")
+	tree.restoreState(old)
+
+	node := tree.getCurrentNode()
+	if node == nil || node.Path != "root/b/two" {
+		t.Errorf("Expected the cursor to stay on root/b/two, got %+v", node)
+	}
+	if len(tree.FlatView) != 3 || tree.FlatView[0].Expanded {
+		t.Errorf("Expected only b expanded, got %d rows", len(tree.FlatView))
+	}
+}
+
+func TestTUICleanCancelledAfterCurrentItem(t *testing.T) {
+	tempDir := t.TempDir()
+	setupTestProject(t, filepath.Join(tempDir, "one"), "one", "Python")
+	setupTestProject(t, filepath.Join(tempDir, "two"), "two", "Node.js")
+	for _, dir := range []string{".pytest_cache", ".mypy_cache"} {
+		os.MkdirAll(filepath.Join(tempDir, "one", dir), 0755)
+		os.WriteFile(filepath.Join(tempDir, "one", dir, "cache"), []byte("cache"), 0644)
+	}
+	history := cacheremover.NewHistory(filepath.Join(tempDir, "history.jsonl"))
+
+	scanner, cleaner, _ := newTestRun(t)
+	var projects []ProjectItem
+	for _, project := range scanTestProjects(t, scanner, tempDir) {
+		projects = append(projects, newProjectItem(scanner, project))
+	}
+	if len(projects) != 2 || len(projects[0].CacheItems) < 2 {
+		t.Fatalf("Expected two projects, the first with several items, got %+v", projects)
+	}
+
+	// Stop while the first item is being removed
+	job, ctx := newCleanJob(projects, 1)
+	cleaner.OnProgress = func(progress cacheremover.CleanProgress) {
+		job.itemProgress(progress)
+		if !progress.Done {
+			job.cancel()
+		}
+	}
+	job.run(ctx, cleaner, history, tempDir, projects)
+
+	msg := <-job.done
+	first, second := msg.outcomes[0], msg.outcomes[1]
+	if first.result == nil || len(first.result.Removed) != 1 || len(first.result.Skipped) != len(projects[0].CacheItems)-1 {
+		t.Errorf("Expected the first item removed and the others skipped, got %+v", first.result)
+	}
+	if second.result != nil {
+		t.Errorf("Expected the second project left alone, got %+v", second.result)
+	}
+	for _, item := range append(first.result.Skipped, projects[1].CacheItems...) {
+		if _, err := os.Lstat(item.Path); err != nil {
+			t.Errorf("Expected %s to be left in place: %v", item.Path, err)
+		}
+	}
+	if msg.results.TotalCacheItems != 1 || job.latest.itemsRemoved != 1 || job.latest.bytesFreed != msg.results.TotalSizeRemoved {
+		t.Errorf("Expected one item reported removed, got %+v and %+v", msg.results, job.latest)
+	}
+
+	records, err := history.Records()
+	if err != nil || len(records) != 1 || records[0].ItemsRemoved != 1 || records[0].Source != "tui" {
+		t.Errorf("Expected the partial clean in the history, got %+v (%v)", records, err)
+	}
+}
+
+func TestTUICleanInParallel(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"api", "shop", "site", "tools", "web"} {
+		setupTestProject(t, filepath.Join(tempDir, name), name, "Node.js")
+	}
+	history := cacheremover.NewHistory(filepath.Join(tempDir, "history.jsonl"))
+
+	scanner, cleaner, _ := newTestRun(t)
+	var projects []ProjectItem
+	for _, project := range scanTestProjects(t, scanner, tempDir) {
+		projects = append(projects, newProjectItem(scanner, project))
+	}
+
+	// The first item removed waits until another project is being cleaned,
+	// which only happens when projects are cleaned concurrently
+	job, ctx := newCleanJob(projects, 3)
+	var first sync.Once
+	cleaner.OnProgress = func(progress cacheremover.CleanProgress) {
+		job.itemProgress(progress)
+		first.Do(func() {
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				job.mu.Lock()
+				cleaning := len(job.latest.cleaning)
+				job.mu.Unlock()
+				if cleaning > 1 {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Error("Expected another project to be cleaned while the first item was removed")
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+	go job.run(ctx, cleaner, history, tempDir, projects)
+
+	var msg tea.Msg
+	for {
+		msg = job.wait()()
+		if _, ok := msg.(cleanProgressMsg); !ok {
+			break
+		}
+	}
+
+	complete := msg.(cleanCompleteMsg)
+	for i, outcome := range complete.outcomes {
+		if outcome.project.Project.Path != projects[i].Project.Path || outcome.result == nil || len(outcome.result.Removed) != len(projects[i].CacheItems) {
+			t.Errorf("%s: expected every item removed, got %+v", projects[i].Project.Path, outcome.result)
+		}
+	}
+	if complete.results.TotalProjects != len(projects) {
+		t.Errorf("Expected %d projects cleaned, got %d", len(projects), complete.results.TotalProjects)
+	}
+	latest := job.latest
+	if latest.projectsDone != len(projects) || latest.bytesDone != job.bytes || len(latest.cleaning) != 0 || len(latest.removing) != 0 {
+		t.Errorf("Expected the progress to account for everything, got %+v of %d bytes", latest, job.bytes)
+	}
+
+	// The history lists the projects in the order they were selected
+	records, err := history.Records()
+	if err != nil || len(records) != 1 || len(records[0].Projects) != len(projects) {
+		t.Fatalf("Expected one history record with every project, got %+v (%v)", records, err)
+	}
+	for i, project := range records[0].Projects {
+		if project.Path != projects[i].Project.Path {
+			t.Errorf("Expected %s at position %d of the history, got %s", projects[i].Project.Path, i, project.Path)
+		}
+	}
+}
+
+func TestTargetFreePlan(t *testing.T) {
+	tempDir := t.TempDir()
+	setupSizedProject(t, filepath.Join(tempDir, "a-small"), 10*1024)
+	setupSizedProject(t, filepath.Join(tempDir, "b-large"), 30*1024)
+	setupSizedProject(t, filepath.Join(tempDir, "c-medium"), 20*1024)
+
+	opts := cleanOptions{workers: 2, dryRun: true, targetFree: 40 * 1024}
+	doc := runJSON(t, tempDir, opts)
+
+	statuses := make(map[string]string)
+	var plannedSize int64
+	for _, project := range doc.Projects {
+		statuses[filepath.Base(project.Path)] = project.Status
+		if project.Status == statusWouldRemove {
+			plannedSize += project.TotalSize
+		}
+	}
+	if doc.Target == nil || doc.Target.PlannedProjects != 2 || doc.Target.PlannedSize != plannedSize {
+		t.Fatalf("Unexpected plan: %+v", doc.Target)
+	}
+	expected := map[string]string{
+		"a-small":  statusNotNeeded,
+		"b-large":  statusWouldRemove,
+		"c-medium": statusWouldRemove,
+	}
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Errorf("Expected %s to be %s, got %s", name, status, statuses[name])
+		}
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	now := time.Now()
+	fresh := &cacheremover.Project{Path: "fresh", TotalSize: 300, LastActivity: now}
+	stale := &cacheremover.Project{Path: "stale", TotalSize: 100, LastActivity: now.Add(-90 * 24 * time.Hour)}
+	tiny := &cacheremover.Project{Path: "tiny", TotalSize: 10}
+
+	projects := []*cacheremover.Project{tiny, fresh, stale}
+	rankCandidates(projects, now)
+
+	// stale scores 100 * (1 + 3 months), ahead of the larger but active project
+	if projects[0] != stale || projects[1] != fresh || projects[2] != tiny {
+		t.Errorf("Unexpected ranking: %s, %s, %s", projects[0].Path, projects[1].Path, projects[2].Path)
+	}
+}
+
+func TestFreeSpaceTarget(t *testing.T) {
+	usage := cacheremover.DiskUsage{Total: 1000, Available: 80}
+
+	low, err := parseFreeThreshold("10%")
+	if err != nil {
+		t.Fatalf("parseFreeThreshold failed: %v", err)
+	}
+	if target := freeSpaceTarget(usage, low, low.double()); target != 120 {
+		t.Errorf("Expected to reclaim 120 bytes to reach 20%%, got %d", target)
+	}
+
+	high, err := parseFreeThreshold("500")
+	if err != nil {
+		t.Fatalf("parseFreeThreshold failed: %v", err)
+	}
+	if target := freeSpaceTarget(usage, low, high); target != 420 {
+		t.Errorf("Expected to reclaim 420 bytes to reach 500 free, got %d", target)
+	}
+
+	usage.Available = 100
+	if target := freeSpaceTarget(usage, low, high); target != 0 {
+		t.Errorf("Free space at the threshold should need no cleanup, got %d", target)
+	}
+
+	for _, invalid := range []string{"0%", "150%", "lots"} {
+		if _, err := parseFreeThreshold(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestNothingToFreeStillReports(t *testing.T) {
+	rootDir := t.TempDir()
+	free, err := checkFreeSpace(io.Discard, rootDir, "1", "")
+	if err != nil || free.Target != 0 || free.Available == 0 {
+		t.Fatalf("Expected enough free space, got %+v (%v)", free, err)
+	}
+
+	// Scripts get the usual empty document, with the free-space figures
+	var buf bytes.Buffer
+	out, _ := newReporter(outputJSON, &buf, false)
+	out.begin(rootDir, cleanOptions{freeSpace: free}, nil)
+	out.end(&cacheremover.CleanupStats{})
+	var doc jsonDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || doc.Stats == nil || doc.Projects == nil {
+		t.Fatalf("Expected an empty summary document, got %s (%v)", buf.String(), err)
+	}
+	if doc.FreeSpace == nil || *doc.FreeSpace != *free {
+		t.Errorf("Expected free space %+v in the document, got %+v", free, doc.FreeSpace)
+	}
+
+	buf.Reset()
+	out, _ = newReporter(outputNDJSON, &buf, false)
+	out.begin(rootDir, cleanOptions{freeSpace: free}, nil)
+	out.end(&cacheremover.CleanupStats{})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var start ndjsonStart
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &start) != nil || !strings.Contains(lines[1], `"summary"`) {
+		t.Fatalf("Expected start and summary events, got %s", buf.String())
+	}
+	if start.FreeSpace == nil || *start.FreeSpace != *free {
+		t.Errorf("Expected free space %+v in the start event, got %+v", free, start.FreeSpace)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"512", 512},
+		{"10K", 10 * 1024},
+		{"500M", 500 * 1024 * 1024},
+		{"20G", 20 * 1024 * 1024 * 1024},
+		{"1.5GB", 3 * 512 * 1024 * 1024},
+		{"2GiB", 2 * 1024 * 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		result, err := parseSize(test.input)
+		if err != nil || result != test.expected {
+			t.Errorf("parseSize(%q) = %d, %v, expected %d", test.input, result, err, test.expected)
+		}
+	}
+
+	if _, err := parseSize("lots"); err == nil {
+		t.Error("Expected an error for an invalid size")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"30d", 30 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"12h", 12 * time.Hour},
+		{"7", 7 * 24 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+	}
+
+	for _, test := range tests {
+		result, err := parseAge(test.input)
+		if err != nil || result != test.expected {
+			t.Errorf("parseAge(%q) = %v, %v; expected %v", test.input, result, err, test.expected)
+		}
+	}
+
+	for _, invalid := range []string{"", "abc", "-3d", "d"} {
+		if _, err := parseAge(invalid); err == nil {
+			t.Errorf("parseAge(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestHistoryRecordedForRealClean(t *testing.T) {
+	tempDir := t.TempDir()
+	setupTestProject(t, filepath.Join(tempDir, "react-app"), "react-app", "Node.js")
+	history := cacheremover.NewHistory(filepath.Join(tempDir, "history.jsonl"))
+
+	for _, dryRun := range []bool{true, false} {
+		scanner, cleaner, out := newTestRun(t)
+		out = &historyReporter{reporter: out, history: history}
+		opts := cleanOptions{workers: 1, dryRun: dryRun}
+
+		out.begin(tempDir, opts, scanner.Config())
+		projects := scanTestProjects(t, scanner, tempDir)
+		out.scanned(len(projects))
+		stats := &cacheremover.CleanupStats{}
+		processProjects(context.Background(), cleaner, projects, opts, out, stats)
+		out.end(stats)
+	}
+
+	// Only the real clean is recorded
+	records, err := history.Records()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected 1 history record, got %d (%v)", len(records), err)
+	}
+	if records[0].ItemsRemoved != 1 || records[0].BytesReclaimed <= 0 || records[0].Source != "cli" {
+		t.Errorf("Unexpected history record: %+v", records[0])
 	}
 }
 
@@ -289,6 +822,7 @@ func setupTestProject(t *testing.T, projectDir, name, projectType string) {
 			content := strings.Repeat("// module content\n", 100) // ~1.8KB per file
 			os.WriteFile(filePath, []byte(content), 0644)
 		}
+		os.WriteFile(filepath.Join(nodeModules, ".package-lock.json"), []byte(`{"lockfileVersion": 3}`), 0644)
 
 	case "Python":
 		// Create requirements.txt
@@ -327,3 +861,116 @@ func setupTestProject(t *testing.T, projectDir, name, projectType string) {
 		os.WriteFile(classFile, []byte(content), 0644)
 	}
 }
+
+// setupSyntheticTree creates a tree shaped like a developer's home
+// directory: groups of Node.js, Python and Maven projects, each with nested
+// source folders and filled cache directories, next to plain folders
+func setupSyntheticTree(b *testing.B, root string, projects int) {
+	b.Helper()
+	layouts := []struct {
+		indicator string
+		caches    []string
+	}{
+		{"package.json", []string{"node_modules", "dist"}},
+		{"requirements.txt", []string{"venv", "__pycache__"}},
+		{"pom.xml", []string{"target"}},
+	}
+
+	write := func(path string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("synthetic\n"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	// tree writes width files into each directory, depth levels deep
+	var tree func(dir string, depth, width int)
+	tree = func(dir string, depth, width int) {
+		for i := 0; i < width; i++ {
+			write(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)))
+			if depth > 1 {
+				tree(filepath.Join(dir, fmt.Sprintf("dir%d", i)), depth-1, width)
+			}
+		}
+	}
+
+	for i := 0; i < projects; i++ {
+		layout := layouts[i%len(layouts)]
+		group := filepath.Join(root, fmt.Sprintf("group%d", i%8))
+		project := filepath.Join(group, fmt.Sprintf("project%d", i))
+		write(filepath.Join(project, layout.indicator))
+		tree(filepath.Join(project, "src"), 3, 4)
+		for _, cache := range layout.caches {
+			tree(filepath.Join(project, cache), 3, 6)
+		}
+		write(filepath.Join(project, "venv", "pyvenv.cfg"))
+		tree(filepath.Join(group, fmt.Sprintf("notes%d", i)), 2, 4)
+	}
+}
+
+func newBenchmarkScanner() *cacheremover.Scanner {
+	config := cacheremover.DefaultConfig()
+	return cacheremover.NewScanner(&config)
+}
+
+// BenchmarkScan measures the single walk that finds projects and sizes
+// their cache items
+func BenchmarkScan(b *testing.B) {
+	root := b.TempDir()
+	setupSyntheticTree(b, root, 60)
+	scanner := newBenchmarkScanner()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		projects, err := scanner.Scan(context.Background(), root)
+		if err != nil || len(projects) != 60 {
+			b.Fatalf("Scan found %d projects: %v", len(projects), err)
+		}
+	}
+}
+
+// BenchmarkScanWithActivity adds the activity measurement of --older-than
+func BenchmarkScanWithActivity(b *testing.B) {
+	root := b.TempDir()
+	setupSyntheticTree(b, root, 60)
+	scanner := newBenchmarkScanner()
+	scanner.MeasureActivity = true
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := scanner.Scan(context.Background(), root); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFindProjects measures project discovery alone, which skips
+// cache directories
+func BenchmarkFindProjects(b *testing.B) {
+	root := b.TempDir()
+	setupSyntheticTree(b, root, 60)
+	scanner := newBenchmarkScanner()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := scanner.FindProjects(context.Background(), root); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkScanProject measures scanning a single large project
+func BenchmarkScanProject(b *testing.B) {
+	root := b.TempDir()
+	setupSyntheticTree(b, root, 1)
+	project := filepath.Join(root, "group0", "project0")
+	scanner := newBenchmarkScanner()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := scanner.ScanProject(context.Background(), project); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cache-remover-utility/cacheremover"
+)
+
+// Output formats accepted by --output
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+// Project statuses reported in machine-readable output
+const (
+	statusClean       = "clean"        // No cache items found
+	statusWouldRemove = "would_remove" // Dry run
+	statusRemoved     = "removed"      // Cache items were removed (possibly with failures)
+	statusTrashed     = "trashed"      // Cache items were moved to the trash
+	statusSkipped     = "skipped"      // Declined at the interactive prompt
+	statusActive      = "active"       // Worked on more recently than --older-than
+	statusTooSmall    = "too_small"    // Cache below --min-project-size
+	statusNotNeeded   = "not_needed"   // --target-free was reached before this project
+)
+
+// projectReport is the outcome of processing a single project
+type projectReport struct {
+	Path         string                   `json:"path"`
+	Type         string                   `json:"type"`
+	Types        []string                 `json:"types,omitempty"`
+	Status       string                   `json:"status"`
+	Items        []cacheremover.CacheItem `json:"items"`
+	TotalSize    int64                    `json:"total_size"`
+	ApparentSize int64                    `json:"apparent_size"`
+	RemovedItems int                      `json:"removed_items"`
+	RemovedSize  int64                    `json:"removed_size"`
+	Failures     []failureReport          `json:"failures,omitempty"`
+	LastActivity *time.Time               `json:"last_activity,omitempty"`
+	Members      []memberReport           `json:"members,omitempty"`
+	Commands     []commandReport          `json:"commands,omitempty"`
+
+	result *cacheremover.CleanResult
+}
+
+// memberReport is a workspace member of a project and the cache items
+// attributed to it. Its items are also part of the project's items.
+type memberReport struct {
+	Path      string                   `json:"path"`
+	Type      string                   `json:"type"`
+	Items     []cacheremover.CacheItem `json:"items"`
+	TotalSize int64                    `json:"total_size"`
+}
+
+// targetReport is the plan of a --target-free run
+type targetReport struct {
+	Bytes           int64 `json:"bytes"`
+	PlannedProjects int   `json:"planned_projects"`
+	PlannedSize     int64 `json:"planned_size"`
+}
+
+// Clean command statuses
+const (
+	commandPlanned = "planned" // Dry run
+	commandOK      = "ok"
+	commandFailed  = "failed"
+	commandMissing = "missing" // The program is not installed
+)
+
+// commandReport is a clean command run, or planned, for a project
+type commandReport struct {
+	Command string `json:"command"`
+	Dir     string `json:"dir"`
+	Mode    string `json:"mode"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+func newCommandReport(planned cacheremover.PlannedCommand, status string) commandReport {
+	return commandReport{Command: planned.Command.String(), Dir: planned.Dir, Mode: commandMode(planned.Command), Status: status}
+}
+
+// commandMode returns the mode of a clean command, filling in the default
+func commandMode(command *cacheremover.CleanCommand) string {
+	if command.Mode == "" {
+		return cacheremover.CommandBefore
+	}
+	return command.Mode
+}
+
+type failureReport struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+	// Paths lists what could not be removed when an item was removed partially
+	Paths []failureReport `json:"paths,omitempty"`
+}
+
+func newFailureReport(failure cacheremover.RemoveFailure) failureReport {
+	report := failureReport{Path: failure.Item.Path, Error: failure.Err.Error()}
+	var removeErr *cacheremover.RemoveError
+	if errors.As(failure.Err, &removeErr) {
+		for _, pathErr := range removeErr.Failures {
+			report.Paths = append(report.Paths, failureReport{Path: pathErr.Path, Error: pathErr.Err.Error()})
+		}
+	}
+	return report
+}
+
+func newProjectReport(project *cacheremover.Project) projectReport {
+	items := project.Items
+	if items == nil {
+		items = []cacheremover.CacheItem{}
+	}
+	report := projectReport{
+		Path:         project.Path,
+		Type:         project.Type.Name,
+		Status:       statusClean,
+		Items:        items,
+		TotalSize:    project.TotalSize,
+		ApparentSize: project.ApparentSize,
+	}
+	if len(project.Types) > 1 {
+		for _, pt := range project.Types {
+			report.Types = append(report.Types, pt.Name)
+		}
+	}
+	if !project.LastActivity.IsZero() {
+		lastActivity := project.LastActivity
+		report.LastActivity = &lastActivity
+	}
+	for _, member := range project.Members {
+		items := member.Items
+		if items == nil {
+			items = []cacheremover.CacheItem{}
+		}
+		report.Members = append(report.Members, memberReport{
+			Path:      member.Path,
+			Type:      member.Type.Name,
+			Items:     items,
+			TotalSize: member.TotalSize,
+		})
+	}
+	return report
+}
+
+// idleSuffix describes how long ago a project was last worked on, if known
+func idleSuffix(lastActivity time.Time) string {
+	if lastActivity.IsZero() {
+		return ""
+	}
+	return ", idle " + formatAge(time.Since(lastActivity))
+}
+
+// setResult records the outcome of a real clean on the report
+func (r *projectReport) setResult(result *cacheremover.CleanResult) {
+	r.Status = statusRemoved
+	if result.Trashed {
+		r.Status = statusTrashed
+	}
+	r.result = result
+	r.RemovedItems = len(result.Removed)
+	r.RemovedSize = result.BytesRemoved
+	for _, run := range result.Commands {
+		command := newCommandReport(run.PlannedCommand, commandOK)
+		switch {
+		case run.Missing:
+			command.Status = commandMissing
+		case run.Err != nil:
+			command.Status = commandFailed
+			command.Error = run.Err.Error()
+			command.Output = run.Output
+		}
+		r.Commands = append(r.Commands, command)
+	}
+	for _, failure := range result.Failed {
+		r.Failures = append(r.Failures, newFailureReport(failure))
+	}
+}
+
+// reporter renders the progress and results of a CLI run. Methods called
+// from processProject must be safe for concurrent use by the workers.
+type reporter interface {
+	begin(rootDir string, opts cleanOptions, config *cacheremover.Config)
+	scanEvent(event cacheremover.Event)
+	scanned(projectCount int)
+	planned(target int64, projectCount int, size int64)
+	projectStarted(project *cacheremover.Project)
+	projectFinished(report projectReport)
+	warning(format string, args ...interface{})
+	end(stats *cacheremover.CleanupStats)
+}
+
+// newReporter creates the reporter for an output format
+func newReporter(format string, w io.Writer, verbose bool) (reporter, error) {
+	switch format {
+	case outputText:
+		return &textReporter{w: w, verbose: verbose}, nil
+	case outputJSON:
+		return &jsonReporter{w: w}, nil
+	case outputNDJSON:
+		return &ndjsonReporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (expected text, json or ndjson)", format)
+	}
+}
+
+// textReporter prints the human-readable output with emoji
+type textReporter struct {
+	w       io.Writer
+	verbose bool
+	found   int
+}
+
+func (r *textReporter) begin(rootDir string, opts cleanOptions, config *cacheremover.Config) {
+	fmt.Fprintf(r.w, "🧹 Cache Remover Utility\n")
+	fmt.Fprintf(r.w, "Scanning directory: %s\n", rootDir)
+	fmt.Fprintf(r.w, "Workers: %d\n", opts.workers)
+	if opts.dryRun {
+		fmt.Fprintf(r.w, "🔍 DRY RUN MODE - No files will be removed\n")
+	}
+	if opts.trash {
+		fmt.Fprintf(r.w, "🗑️  TRASH MODE - Cache items will be moved to the trash (undo with 'restore')\n")
+	}
+
+	// Display supported project types for transparency
+	var typeNames []string
+	for _, pt := range config.ProjectTypes {
+		typeNames = append(typeNames, pt.Name)
+	}
+	fmt.Fprintf(r.w, "🔧 Supported project types: %s\n", strings.Join(typeNames, ", "))
+
+	fmt.Fprintf(r.w, "💡 Tip: Use --ui for terminal interface or --web for browser interface\n")
+	fmt.Fprintln(r.w)
+}
+
+func (r *textReporter) scanEvent(event cacheremover.Event) {
+	if event.Kind == cacheremover.EventTrackedSkipped {
+		// Always shown: the user should know why an expected item was kept
+		fmt.Fprintf(r.w, "🔒 Keeping git-tracked %s (use --include-tracked to remove it)\n", event.Path)
+		return
+	}
+	if event.Kind == cacheremover.EventGitIndexUnreadable {
+		fmt.Fprintf(r.w, "🔒 Keeping %s, tracked files cannot be ruled out: %v (use --include-tracked to remove it)\n", event.Path, event.Err)
+		return
+	}
+	if !r.verbose {
+		return
+	}
+	switch event.Kind {
+	case cacheremover.EventAccessError:
+		fmt.Fprintf(r.w, "⚠️  Warning: Cannot access %s: %v\n", event.Path, event.Err)
+	case cacheremover.EventCacheDirSkipped:
+		fmt.Fprintf(r.w, "⏭️  Skipping cache directory: %s\n", event.Path)
+	case cacheremover.EventProjectFound:
+		fmt.Fprintf(r.w, "📁 Found project: %s\n", event.Path)
+	case cacheremover.EventExcluded:
+		fmt.Fprintf(r.w, "🛡️  Keeping excluded %s\n", event.Path)
+	case cacheremover.EventSignatureMismatch:
+		fmt.Fprintf(r.w, "⏭️  Skipped: signature mismatch: %s\n", event.Path)
+	case cacheremover.EventMountPointSkipped:
+		fmt.Fprintf(r.w, "⏭️  Skipping mount point: %s\n", event.Path)
+	}
+}
+
+func (r *textReporter) scanned(projectCount int) {
+	r.found = projectCount
+	fmt.Fprintf(r.w, "Found %d projects\n\n", projectCount)
+	if projectCount == 0 {
+		fmt.Fprintln(r.w, "No projects found.")
+	}
+}
+
+func (r *textReporter) planned(target int64, projectCount int, size int64) {
+	fmt.Fprintf(r.w, "🎯 Target: free %s - %d projects (%s) selected, largest and oldest first\n",
+		formatBytes(target), projectCount, formatBytes(size))
+	if size < target {
+		fmt.Fprintf(r.w, "⚠️  Only %s of cache found; all of it will be cleaned\n", formatBytes(size))
+	}
+	fmt.Fprintln(r.w)
+}
+
+func (r *textReporter) projectStarted(project *cacheremover.Project) {
+	if r.verbose {
+		fmt.Fprintf(r.w, "🔍 Processing %s project: %s\n", project.Type.Name, project.Path)
+	}
+	if len(project.Items) == 0 {
+		return
+	}
+
+	fmt.Fprintf(r.w, "🗂️  %s (%s): %d cache items (%s)%s%s\n",
+		filepath.Base(project.Path),
+		project.Type.Name,
+		len(project.Items),
+		formatBytes(project.TotalSize),
+		idleSuffix(project.LastActivity),
+		workspaceSuffix(project))
+
+	var withCache []*cacheremover.Project
+	for _, member := range project.Members {
+		if len(member.Items) > 0 {
+			withCache = append(withCache, member)
+		}
+	}
+	for i, member := range withCache {
+		branch := "├─"
+		if i == len(withCache)-1 {
+			branch = "└─"
+		}
+		rel, _ := filepath.Rel(project.Path, member.Path)
+		fmt.Fprintf(r.w, "   %s %s (%s): %d cache items (%s)\n",
+			branch, filepath.ToSlash(rel), member.Type.Name, len(member.Items), formatBytes(member.TotalSize))
+	}
+}
+
+// workspaceSuffix describes the members of a workspace root, if any
+func workspaceSuffix(project *cacheremover.Project) string {
+	switch len(project.Members) {
+	case 0:
+		return ""
+	case 1:
+		return ", workspace with 1 member"
+	}
+	return fmt.Sprintf(", workspace with %d members", len(project.Members))
+}
+
+func (r *textReporter) projectFinished(report projectReport) {
+	switch report.Status {
+	case statusClean:
+		if r.verbose {
+			fmt.Fprintf(r.w, "✅ No cache found in: %s\n", report.Path)
+		}
+		return
+
+	case statusSkipped:
+		fmt.Fprintf(r.w, "⏭️  Skipped: %s\n", report.Path)
+		return
+
+	case statusTooSmall:
+		if r.verbose {
+			fmt.Fprintf(r.w, "🔹 Skipping small cache (%s): %s\n", formatBytes(report.TotalSize), report.Path)
+		}
+		return
+
+	case statusNotNeeded:
+		if r.verbose {
+			fmt.Fprintf(r.w, "✋ Not needed to reach target (%s): %s\n", formatBytes(report.TotalSize), report.Path)
+		}
+		return
+
+	case statusActive:
+		if r.verbose {
+			fmt.Fprintf(r.w, "⏳ Skipping active project (last activity %s ago): %s\n",
+				formatAge(time.Since(*report.LastActivity)), report.Path)
+		}
+		return
+
+	case statusWouldRemove:
+		fmt.Fprintf(r.w, "🔍 Would remove %d items (%s) from: %s\n",
+			len(report.Items), formatBytes(report.TotalSize), report.Path)
+		for _, command := range report.Commands {
+			fmt.Fprintf(r.w, "  $ %s (in %s, %s)\n", command.Command, command.Dir, command.Mode)
+		}
+		for _, item := range report.Items {
+			fmt.Fprintf(r.w, "  - %s (%s)%s%s\n", item.Path, formatBytes(item.Size), symlinkSuffix(item), gitStatusSuffix(item))
+		}
+
+	case statusRemoved, statusTrashed:
+		for _, command := range report.Commands {
+			r.printCommand(command)
+		}
+		for _, failure := range report.result.Failed {
+			// Always log removal failures, not just in verbose mode
+			fmt.Fprintf(r.w, "❌ Failed to remove %s: %v\n", failure.Item.Path, failure.Err)
+		}
+		if r.verbose {
+			for _, item := range report.result.Removed {
+				fmt.Fprintf(r.w, "🗑️  Removed: %s (%s)\n", item.Path, formatBytes(item.Size))
+			}
+		}
+		if report.RemovedItems > 0 && report.Status == statusTrashed {
+			fmt.Fprintf(r.w, "🗑️  Moved %d items (%s) to trash from: %s\n",
+				report.RemovedItems, formatBytes(report.RemovedSize), report.Path)
+		} else if report.RemovedItems > 0 {
+			fmt.Fprintf(r.w, "✅ Removed %d items (%s) from: %s\n",
+				report.RemovedItems, formatBytes(report.RemovedSize), report.Path)
+		}
+	}
+	fmt.Fprintln(r.w)
+}
+
+// printCommand prints the outcome of a clean command. Failures are always
+// shown with the tool's output, since it explains what went wrong.
+func (r *textReporter) printCommand(command commandReport) {
+	switch command.Status {
+	case commandOK:
+		fmt.Fprintf(r.w, "🔧 Ran %s in %s\n", command.Command, command.Dir)
+	case commandMissing:
+		if r.verbose {
+			fmt.Fprintf(r.w, "➖ Not installed, skipped: %s\n", command.Command)
+		}
+	case commandFailed:
+		fmt.Fprintf(r.w, "❌ %s failed in %s: %s\n", command.Command, command.Dir, command.Error)
+		for _, line := range strings.Split(command.Output, "\n") {
+			if line != "" {
+				fmt.Fprintf(r.w, "   │ %s\n", line)
+			}
+		}
+	}
+}
+
+// symlinkSuffix marks items that are symlinks; only the link is removed
+func symlinkSuffix(item cacheremover.CacheItem) string {
+	if item.Type != "symlink" {
+		return ""
+	}
+	if target, err := os.Readlink(item.Path); err == nil {
+		return " -> " + target + " (link only)"
+	}
+	return " (link only)"
+}
+
+// gitStatusSuffix returns the git status of item for display, if it has one
+func gitStatusSuffix(item cacheremover.CacheItem) string {
+	if item.GitStatus == "" {
+		return ""
+	}
+	return " [" + item.GitStatus + "]"
+}
+
+func (r *textReporter) warning(format string, args ...interface{}) {
+	fmt.Fprintf(r.w, "⚠️  Warning: "+format+"\n", args...)
+}
+
+func (r *textReporter) end(stats *cacheremover.CleanupStats) {
+	if r.found == 0 {
+		return
+	}
+	printStats(r.w, stats)
+}
+
+// jsonReporter collects every project and writes a single document at the end
+type jsonReporter struct {
+	w         io.Writer
+	mu        sync.Mutex
+	rootDir   string
+	dryRun    bool
+	freeSpace *freeSpaceReport
+	target    *targetReport
+	projects  []projectReport
+}
+
+type jsonDocument struct {
+	Root      string                     `json:"root"`
+	DryRun    bool                       `json:"dry_run"`
+	FreeSpace *freeSpaceReport           `json:"free_space,omitempty"`
+	Target    *targetReport              `json:"target,omitempty"`
+	Projects  []projectReport            `json:"projects"`
+	Stats     *cacheremover.CleanupStats `json:"stats"`
+}
+
+func (r *jsonReporter) begin(rootDir string, opts cleanOptions, config *cacheremover.Config) {
+	r.rootDir = rootDir
+	r.dryRun = opts.dryRun
+	r.freeSpace = opts.freeSpace
+	r.projects = []projectReport{}
+}
+
+func (r *jsonReporter) scanEvent(event cacheremover.Event) {}
+func (r *jsonReporter) scanned(projectCount int)           {}
+
+func (r *jsonReporter) planned(target int64, projectCount int, size int64) {
+	r.target = &targetReport{Bytes: target, PlannedProjects: projectCount, PlannedSize: size}
+}
+func (r *jsonReporter) projectStarted(project *cacheremover.Project) {}
+
+func (r *jsonReporter) projectFinished(report projectReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.projects = append(r.projects, report)
+}
+
+func (r *jsonReporter) warning(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
+}
+
+func (r *jsonReporter) end(stats *cacheremover.CleanupStats) {
+	// Workers finish in arbitrary order; sort for stable output
+	sort.Slice(r.projects, func(i, j int) bool {
+		return r.projects[i].Path < r.projects[j].Path
+	})
+
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	enc.Encode(jsonDocument{
+		Root:      r.rootDir,
+		DryRun:    r.dryRun,
+		FreeSpace: r.freeSpace,
+		Target:    r.target,
+		Projects:  r.projects,
+		Stats:     stats,
+	})
+}
+
+// ndjsonReporter streams one JSON event per line as workers finish
+type ndjsonReporter struct {
+	enc *json.Encoder
+	mu  sync.Mutex
+}
+
+type ndjsonStart struct {
+	Event     string           `json:"event"`
+	Root      string           `json:"root"`
+	DryRun    bool             `json:"dry_run"`
+	FreeSpace *freeSpaceReport `json:"free_space,omitempty"`
+}
+
+type ndjsonScanned struct {
+	Event        string `json:"event"`
+	ProjectCount int    `json:"project_count"`
+}
+
+type ndjsonPlan struct {
+	Event string `json:"event"`
+	targetReport
+}
+
+type ndjsonProject struct {
+	Event string `json:"event"`
+	projectReport
+}
+
+type ndjsonSkipped struct {
+	Event  string `json:"event"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type ndjsonSummary struct {
+	Event string                     `json:"event"`
+	Stats *cacheremover.CleanupStats `json:"stats"`
+}
+
+func (r *ndjsonReporter) emit(v interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(v)
+}
+
+func (r *ndjsonReporter) begin(rootDir string, opts cleanOptions, config *cacheremover.Config) {
+	r.emit(ndjsonStart{Event: "start", Root: rootDir, DryRun: opts.dryRun, FreeSpace: opts.freeSpace})
+}
+
+func (r *ndjsonReporter) scanEvent(event cacheremover.Event) {
+	switch event.Kind {
+	case cacheremover.EventTrackedSkipped:
+		r.emit(ndjsonSkipped{Event: "skipped", Path: event.Path, Reason: "git_tracked"})
+	case cacheremover.EventGitIndexUnreadable:
+		r.emit(ndjsonSkipped{Event: "skipped", Path: event.Path, Reason: "git_index_unreadable"})
+	case cacheremover.EventExcluded:
+		r.emit(ndjsonSkipped{Event: "skipped", Path: event.Path, Reason: "excluded"})
+	case cacheremover.EventSignatureMismatch:
+		r.emit(ndjsonSkipped{Event: "skipped", Path: event.Path, Reason: "signature_mismatch"})
+	case cacheremover.EventMountPointSkipped:
+		r.emit(ndjsonSkipped{Event: "skipped", Path: event.Path, Reason: "mount_point"})
+	}
+}
+
+func (r *ndjsonReporter) scanned(projectCount int) {
+	r.emit(ndjsonScanned{Event: "scanned", ProjectCount: projectCount})
+}
+
+func (r *ndjsonReporter) planned(target int64, projectCount int, size int64) {
+	r.emit(ndjsonPlan{Event: "plan", targetReport: targetReport{Bytes: target, PlannedProjects: projectCount, PlannedSize: size}})
+}
+
+func (r *ndjsonReporter) projectStarted(project *cacheremover.Project) {}
+
+func (r *ndjsonReporter) projectFinished(report projectReport) {
+	r.emit(ndjsonProject{Event: "project", projectReport: report})
+}
+
+func (r *ndjsonReporter) warning(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
+}
+
+func (r *ndjsonReporter) end(stats *cacheremover.CleanupStats) {
+	r.emit(ndjsonSummary{Event: "summary", Stats: stats})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"cache-remover-utility/cacheremover"
+)
+
+// processTarget implements --target-free: it ranks the scanned projects
+// and cleans the best candidates until the target is reclaimed. Later
+// candidates are reported as not needed.
+func processTarget(ctx context.Context, cleaner *cacheremover.Cleaner, projects []*cacheremover.Project, opts cleanOptions, out reporter, stats *cacheremover.CleanupStats) {
+	var candidates []*cacheremover.Project
+	for _, project := range projects {
+		if selectProject(project, opts, out) {
+			candidates = append(candidates, project)
+		}
+	}
+	rankCandidates(candidates, time.Now())
+
+	plannedCount, plannedSize := planTarget(candidates, opts.targetFree)
+	out.planned(opts.targetFree, plannedCount, plannedSize)
+
+	var reclaimed int64
+	for _, project := range candidates {
+		if ctx.Err() != nil {
+			return
+		}
+		if reclaimed >= opts.targetFree && len(project.Items) > 0 {
+			report := newProjectReport(project)
+			report.Status = statusNotNeeded
+			out.projectFinished(report)
+			continue
+		}
+		// Cleaning continues past the plan when removals fail or are declined
+		reclaimed += cleanProject(ctx, cleaner, project, opts, out, stats)
+	}
+}
+
+// rankCandidates orders projects so the best cleanup candidates come first:
+// large caches in projects nobody has touched for a long time. Each month of
+// inactivity counts the cache size once more.
+func rankCandidates(projects []*cacheremover.Project, now time.Time) {
+	score := func(project *cacheremover.Project) float64 {
+		weight := 1.0
+		if !project.LastActivity.IsZero() {
+			weight += now.Sub(project.LastActivity).Hours() / (24 * 30)
+		}
+		return float64(project.TotalSize) * weight
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool {
+		si, sj := score(projects[i]), score(projects[j])
+		if si != sj {
+			return si > sj
+		}
+		return projects[i].Path < projects[j].Path
+	})
+}
+
+// planTarget returns how many of the ranked projects, and how many bytes,
+// are needed to reach target
+func planTarget(ranked []*cacheremover.Project, target int64) (int, int64) {
+	var count int
+	var size int64
+	for _, project := range ranked {
+		if size >= target {
+			break
+		}
+		if len(project.Items) == 0 {
+			continue
+		}
+		count++
+		size += project.TotalSize
+	}
+	return count, size
+}